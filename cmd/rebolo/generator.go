@@ -67,10 +67,22 @@ func NewGenerator() *Generator {
 		"templates/app/src/styles.css.tmpl",
 		"templates/app/views/layouts/application.html.tmpl",
 		"templates/app/views/home/index.html.tmpl",
+		"templates/app/views/shared/flashes.html.tmpl",
+		"templates/app/views/layouts/mailer.html.tmpl",
+		"templates/app/views/layouts/mailer.txt.tmpl",
 		"templates/config/config.yml.tmpl",
 		"templates/resource/model.go.tmpl",
 		"templates/resource/controller.go.tmpl",
 		"templates/resource/migration.sql.tmpl",
+		"templates/auth/user.go.tmpl",
+		"templates/auth/auth_controller.go.tmpl",
+		"templates/auth/users_migration.sql.tmpl",
+		"templates/auth/login.html.tmpl",
+		"templates/auth/signup.html.tmpl",
+		"templates/auth/two_factor_controller.go.tmpl",
+		"templates/auth/two_factor.html.tmpl",
+		"templates/auth/two_factor_setup.html.tmpl",
+		"templates/auth/recovery_codes.html.tmpl",
 	))
 
 	return &Generator{
@@ -111,6 +123,8 @@ func (g *Generator) GenerateApp(name string, frontendFramework string) error {
 		filepath.Join(name, "models"),
 		filepath.Join(name, "views", "home"),
 		filepath.Join(name, "views", "layouts"),
+		filepath.Join(name, "views", "shared"),
+		filepath.Join(name, "views", "mailers"),
 		filepath.Join(name, "public"),
 		filepath.Join(name, "src"),
 		filepath.Join(name, "db", "migrations"),
@@ -130,6 +144,9 @@ func (g *Generator) GenerateApp(name string, frontendFramework string) error {
 		filepath.Join(name, "src", "styles.css"):                    "app/src/styles.css.tmpl",
 		filepath.Join(name, "views", "layouts", "application.html"): "app/views/layouts/application.html.tmpl",
 		filepath.Join(name, "views", "home", "index.html"):          "app/views/home/index.html.tmpl",
+		filepath.Join(name, "views", "shared", "_flashes.html"):     "app/views/shared/flashes.html.tmpl",
+		filepath.Join(name, "views", "layouts", "mailer.html"):      "app/views/layouts/mailer.html.tmpl",
+		filepath.Join(name, "views", "layouts", "mailer.txt"):       "app/views/layouts/mailer.txt.tmpl",
 	}
 	
 	// Use different main.go template based on frontend
@@ -228,6 +245,60 @@ func (g *Generator) GenerateResource(name string, fieldArgs []string) error {
 	return nil
 }
 
+func (g *Generator) GenerateAuth() error {
+	data := ResourceData{
+		Name:      "User",
+		VarName:   "user",
+		Module:    g.getModuleName(),
+		TableName: "users",
+		ViewPath:  "auth",
+		Timestamp: time.Now().Format("20060102150405"),
+	}
+
+	// Create directories
+	os.MkdirAll("models", 0755)
+	os.MkdirAll("controllers", 0755)
+	os.MkdirAll("db/migrations", 0755)
+	os.MkdirAll(filepath.Join("views", data.ViewPath), 0755)
+
+	files := map[string]string{
+		filepath.Join("models", "user.go"):                                    "auth/user.go.tmpl",
+		filepath.Join("controllers", "auth_controller.go"):                    "auth/auth_controller.go.tmpl",
+		filepath.Join("db", "migrations", data.Timestamp+"_create_users.sql"): "auth/users_migration.sql.tmpl",
+		filepath.Join("views", data.ViewPath, "login.html"):                   "auth/login.html.tmpl",
+		filepath.Join("views", data.ViewPath, "signup.html"):                  "auth/signup.html.tmpl",
+		filepath.Join("controllers", "two_factor_controller.go"):              "auth/two_factor_controller.go.tmpl",
+		filepath.Join("views", data.ViewPath, "two_factor.html"):              "auth/two_factor.html.tmpl",
+		filepath.Join("views", data.ViewPath, "two_factor_setup.html"):        "auth/two_factor_setup.html.tmpl",
+		filepath.Join("views", data.ViewPath, "recovery_codes.html"):          "auth/recovery_codes.html.tmpl",
+	}
+
+	for filePath := range files {
+		if _, err := os.Stat(filePath); err == nil {
+			return fmt.Errorf("%s already exists", filePath)
+		}
+	}
+
+	for filePath, tmplName := range files {
+		if err := g.renderTemplate(tmplName, filePath, data); err != nil {
+			return fmt.Errorf("failed to generate %s: %w", filePath, err)
+		}
+	}
+
+	fmt.Printf("✅ Generated authentication\n")
+	fmt.Printf("   - Model: models/user.go\n")
+	fmt.Printf("   - Controllers: controllers/auth_controller.go, controllers/two_factor_controller.go\n")
+	fmt.Printf("   - Migration: db/migrations/%s_create_users.sql\n", data.Timestamp)
+	fmt.Printf("   - Views: views/auth/ (login, signup, two-factor and recovery codes)\n")
+	fmt.Printf("\n🔐 Next steps:\n")
+	fmt.Printf("   1. Add to main.go: controllers.RegisterAuth(app)\n")
+	fmt.Printf("   2. Protect routes: app.GET(\"/dashboard\", app.RequireLogin(handler))\n")
+	fmt.Printf("   3. Run: rebolo db migrate\n")
+	fmt.Printf("   4. Optional: require two-factor authentication with app.EnableTwoFactor()\n")
+
+	return nil
+}
+
 func (g *Generator) renderTemplate(tmplName, filePath string, data interface{}) error {
 	file, err := os.Create(filePath)
 	if err != nil {
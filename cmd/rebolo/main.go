@@ -90,6 +90,21 @@ var resourceCmd = &cobra.Command{
 	},
 }
 
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Generate authentication (users model, login/logout/signup controller and views)",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Generating authentication...")
+
+		generator := NewGenerator()
+		if err := generator.GenerateAuth(); err != nil {
+			fmt.Printf("❌ Failed to generate auth: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
 var taskCmd = &cobra.Command{
 	Use:   "task [task-name] [args...]",
 	Short: "Run a task (like Rake tasks)",
@@ -115,8 +130,10 @@ func init() {
 	rootCmd.AddCommand(generateCmd)
 	rootCmd.AddCommand(dbCmd)
 	rootCmd.AddCommand(taskCmd)
+	rootCmd.AddCommand(maintenanceCmd)
 
 	generateCmd.AddCommand(resourceCmd)
+	generateCmd.AddCommand(authCmd)
 	dbCmd.AddCommand(migrateCmd)
 }
 
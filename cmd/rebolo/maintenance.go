@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/maintenance"
+	"github.com/spf13/cobra"
+)
+
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance [on|off|status]",
+	Short: "Turn maintenance mode on or off",
+	Long: `Put the application in maintenance mode: visitors get a 503 page
+(views/errors/503.html) with Retry-After, while allowed IPs and staff with
+the bypass link keep using the app. Uses the maintenance section of config.yml.`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"on", "off", "status"},
+	Run: func(cmd *cobra.Command, args []string) {
+		store, closeStore, err := openMaintenanceStore()
+		if err != nil {
+			fmt.Printf("❌ Failed to open maintenance store: %v\n", err)
+			os.Exit(1)
+		}
+		defer closeStore()
+
+		ctx := context.Background()
+		switch args[0] {
+		case "on":
+			message, _ := cmd.Flags().GetString("message")
+			retryAfter, _ := cmd.Flags().GetDuration("retry-after")
+			allowIPs, _ := cmd.Flags().GetStringSlice("allow")
+
+			state := &maintenance.State{
+				Message:    message,
+				RetryAfter: retryAfter,
+				AllowIPs:   allowIPs,
+				Secret:     maintenance.NewSecret(),
+				Since:      time.Now(),
+			}
+			if err := store.Save(ctx, state); err != nil {
+				fmt.Printf("❌ Failed to turn maintenance on: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("🚧 Maintenance mode on")
+			printMaintenanceState(state)
+		case "off":
+			if err := store.Clear(ctx); err != nil {
+				fmt.Printf("❌ Failed to turn maintenance off: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("✅ Maintenance mode off")
+		case "status":
+			state, err := store.Load(ctx)
+			if err != nil {
+				fmt.Printf("❌ Failed to read maintenance state: %v\n", err)
+				os.Exit(1)
+			}
+			if state == nil {
+				fmt.Println("✅ Maintenance mode off")
+				return
+			}
+			fmt.Printf("🚧 Maintenance mode on since %s\n", state.Since.Format(time.RFC1123))
+			printMaintenanceState(state)
+		default:
+			fmt.Printf("❌ Unknown maintenance action: %s (use on, off or status)\n", args[0])
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	maintenanceCmd.Flags().StringP("message", "m", "", "Message shown on the 503 page")
+	maintenanceCmd.Flags().Duration("retry-after", 5*time.Minute, "Sent in the Retry-After header")
+	maintenanceCmd.Flags().StringSlice("allow", nil, "IPs or CIDR ranges let through (repeatable)")
+}
+
+// openMaintenanceStore opens the store of the maintenance section of
+// config.yml, connecting to the database for the sql store
+func openMaintenanceStore() (maintenance.Store, func(), error) {
+	config, err := adapters.NewYAMLConfig().Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	if config.Maintenance.Store != "sql" {
+		store, err := maintenance.OpenStore(config.Maintenance.Store, config.Maintenance.File, nil)
+		return store, func() {}, err
+	}
+
+	if config.Database.URL == "" {
+		return nil, nil, fmt.Errorf("no database configured in config.yml")
+	}
+	database, err := adapters.NewDatabaseFactory().CreateDatabase(config.Database.Driver)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := database.ConnectWithDSN(config.Database.URL, false); err != nil {
+		return nil, nil, err
+	}
+	db, ok := database.DB().(*sql.DB)
+	if !ok {
+		database.Close()
+		return nil, nil, fmt.Errorf("database driver %s has no SQL connection", config.Database.Driver)
+	}
+	store, err := maintenance.NewSQLStore(db)
+	if err != nil {
+		database.Close()
+		return nil, nil, err
+	}
+	return store, func() { database.Close() }, nil
+}
+
+func printMaintenanceState(state *maintenance.State) {
+	if state.Message != "" {
+		fmt.Printf("   Message: %s\n", state.Message)
+	}
+	if state.RetryAfter > 0 {
+		fmt.Printf("   Retry-After: %s\n", state.RetryAfter)
+	}
+	if len(state.AllowIPs) > 0 {
+		fmt.Printf("   Allowed IPs: %s\n", strings.Join(state.AllowIPs, ", "))
+	}
+	fmt.Printf("   Staff bypass: open any page with ?maintenance=%s\n", state.Secret)
+}
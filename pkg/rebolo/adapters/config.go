@@ -2,6 +2,7 @@ package adapters
 
 import (
 	"os"
+	"strings"
 	"gopkg.in/yaml.v3"
 	"github.com/Palaciodiego008/rebololang/pkg/rebolo/ports"
 )
@@ -26,6 +27,24 @@ func (c *YAMLConfig) Load() (ports.ConfigData, error) {
 	if data, err := os.ReadFile("config.yml"); err == nil {
 		yaml.Unmarshal(data, &config)
 	}
+
+	// SESSION_SECRET becomes the current secret, configured ones stay valid for rotation
+	if secret := os.Getenv("SESSION_SECRET"); secret != "" {
+		config.Session.Secrets = append([]string{secret}, config.Session.Secrets...)
+	}
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		config.Auth.JWT.Secret = secret
+	}
+
+	// OAUTH_<NAME>_CLIENT_SECRET keeps provider secrets out of config.yml
+	for name, provider := range config.Auth.Providers {
+		env := "OAUTH_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_CLIENT_SECRET"
+		if secret := os.Getenv(env); secret != "" {
+			provider.ClientSecret = secret
+			config.Auth.Providers[name] = provider
+		}
+	}
 	
 	return config, nil
 }
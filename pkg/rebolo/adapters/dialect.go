@@ -0,0 +1,50 @@
+package adapters
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SQL dialects returned by Dialect
+const (
+	DialectPostgres = "postgres"
+	DialectMySQL    = "mysql"
+	DialectSQLite   = "sqlite"
+)
+
+// Dialect detects the SQL dialect from the driver behind db
+func Dialect(db *sql.DB) string {
+	driver := strings.ToLower(fmt.Sprintf("%T", db.Driver()))
+	switch {
+	case strings.Contains(driver, "pq.") || strings.Contains(driver, "pgx") || strings.Contains(driver, "postgres"):
+		return DialectPostgres
+	case strings.Contains(driver, "mysql"):
+		return DialectMySQL
+	default:
+		return DialectSQLite
+	}
+}
+
+// Rebind converts ? placeholders to the style used by db's driver
+// ($1, $2, ... for PostgreSQL)
+// Usage: db.Exec(adapters.Rebind(db, "DELETE FROM sessions WHERE id = ?"), id)
+func Rebind(db *sql.DB, query string) string {
+	if Dialect(db) != DialectPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -9,21 +9,168 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync"
 )
 
+// RequestHelper builds a template helper bound to the request being
+// rendered. It's also called with a nil request when rendering without
+// one, so it must return a usable placeholder in that case.
+type RequestHelper func(r *http.Request) interface{}
+
+// TemplateHelper builds a template helper bound to the request and to the
+// templates being rendered (nil while parsing), e.g. to execute one of them
+type TemplateHelper func(r *http.Request, templates *template.Template) interface{}
+
+var (
+	requestHelpers   = make(map[string]RequestHelper)
+	partialHelpers   = make(map[string]partialHelper)
+	templateHelpers  = make(map[string]TemplateHelper)
+	requestHelpersMu sync.RWMutex
+)
+
+// partialHelper renders an app partial, or a built-in fallback when the
+// app doesn't define one
+type partialHelper struct {
+	partial  string
+	fallback *template.Template
+	data     RequestHelper
+}
+
+// RegisterTemplateHelper adds a request-bound helper available to every
+// template parsed afterwards
+// Usage: adapters.RegisterTemplateHelper("csrf_token", func(r *http.Request) interface{} { ... })
+func RegisterTemplateHelper(name string, helper RequestHelper) {
+	requestHelpersMu.Lock()
+	defer requestHelpersMu.Unlock()
+	requestHelpers[name] = helper
+}
+
+// RegisterTemplatePartial adds a helper rendering the partial template
+// (e.g. shared/_flashes.html) with data(r). Apps override the markup by
+// adding that file under views/; fallback is used otherwise.
+// Usage: adapters.RegisterTemplatePartial("flashes", "shared/_flashes.html", `{{range .}}...{{end}}`, dataFn)
+func RegisterTemplatePartial(name, partial, fallback string, data RequestHelper) {
+	requestHelpersMu.Lock()
+	defer requestHelpersMu.Unlock()
+	partialHelpers[name] = partialHelper{
+		partial:  partial,
+		fallback: template.Must(template.New(partial).Parse(fallback)),
+		data:     data,
+	}
+}
+
+// RegisterTemplateRenderHelper adds a helper with access to the templates
+// being rendered, available to every template parsed afterwards
+// Usage: adapters.RegisterTemplateRenderHelper("cache", func(r *http.Request, t *template.Template) interface{} { ... })
+func RegisterTemplateRenderHelper(name string, helper TemplateHelper) {
+	requestHelpersMu.Lock()
+	defer requestHelpersMu.Unlock()
+	templateHelpers[name] = helper
+}
+
+// helperFuncs binds every registered helper to r. Partials are looked up
+// in templates (nil while parsing).
+func helperFuncs(r *http.Request, templates *template.Template) template.FuncMap {
+	requestHelpersMu.RLock()
+	defer requestHelpersMu.RUnlock()
+
+	funcs := make(template.FuncMap, len(requestHelpers)+len(partialHelpers)+len(templateHelpers))
+	for name, helper := range requestHelpers {
+		funcs[name] = helper(r)
+	}
+	for name, helper := range partialHelpers {
+		funcs[name] = helper.render(r, templates)
+	}
+	for name, helper := range templateHelpers {
+		funcs[name] = helper(r, templates)
+	}
+	return funcs
+}
+
+// render returns the template function executing the partial for r
+func (h partialHelper) render(r *http.Request, templates *template.Template) func() (template.HTML, error) {
+	return func() (template.HTML, error) {
+		if templates == nil {
+			return "", nil
+		}
+
+		tmpl := templates.Lookup(h.partial)
+		if tmpl == nil {
+			tmpl = h.fallback
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, h.data(r)); err != nil {
+			return "", err
+		}
+		return template.HTML(buf.String()), nil
+	}
+}
+
 // HTMLRenderer implements Renderer interface
 type HTMLRenderer struct {
+	// templates is never executed directly so it can be cloned into sets
 	templates *template.Template
+	sets      sync.Pool
+}
+
+// renderSet is a clone of the templates whose helpers call the versions
+// bound to the request it's rendering. Sets are pooled so html/template
+// only escapes each clone once, on its first execution.
+type renderSet struct {
+	templates *template.Template
+	req       *http.Request
+	bound     template.FuncMap
+}
+
+// newRenderSet clones the templates, forwarding every helper to the set
+func (r *HTMLRenderer) newRenderSet() (*renderSet, error) {
+	templates, err := r.templates.Clone()
+	if err != nil {
+		return nil, err
+	}
+	set := &renderSet{templates: templates}
+
+	funcs := helperFuncs(nil, nil)
+	for name, placeholder := range funcs {
+		name := name
+		fnType := reflect.TypeOf(placeholder)
+		funcs[name] = reflect.MakeFunc(fnType, func(args []reflect.Value) []reflect.Value {
+			fn := reflect.ValueOf(set.helper(name))
+			if fnType.IsVariadic() {
+				return fn.CallSlice(args)
+			}
+			return fn.Call(args)
+		}).Interface()
+	}
+	templates.Funcs(funcs)
+	return set, nil
+}
+
+// helper returns the helper name bound to the current request
+func (s *renderSet) helper(name string) interface{} {
+	if s.bound == nil {
+		s.bound = helperFuncs(s.req, s.templates)
+	}
+	return s.bound[name]
 }
 
 func NewHTMLRenderer() *HTMLRenderer {
-	tmpl := template.New("root")
+	tmpl := template.New("root").Funcs(helperFuncs(nil, nil))
 
 	// Walk through views and parse each template with its relative path as name
 	err := filepath.Walk("views", func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		// Mailer templates are rendered by the mail package with their own helpers
+		if info.IsDir() && path == filepath.Join("views", "mailers") {
+			return filepath.SkipDir
+		}
+		if path == filepath.Join("views", "layouts", "mailer.html") {
+			return nil
+		}
 		if !info.IsDir() && filepath.Ext(path) == ".html" {
 			// Read the template file
 			content, err := os.ReadFile(path)
@@ -50,7 +197,7 @@ func NewHTMLRenderer() *HTMLRenderer {
 
 	if err != nil {
 		log.Printf("❌ Error loading templates: %v", err)
-		tmpl = template.New("empty")
+		tmpl = template.New("empty").Funcs(helperFuncs(nil, nil))
 	}
 
 	log.Printf("📝 Total templates loaded: %d", len(tmpl.Templates())-1) // -1 for root
@@ -59,8 +206,28 @@ func NewHTMLRenderer() *HTMLRenderer {
 }
 
 func (r *HTMLRenderer) RenderHTML(w http.ResponseWriter, templateName string, data interface{}) error {
+	return r.RenderHTMLWithRequest(w, nil, templateName, data)
+}
+
+// RenderHTMLWithRequest renders a template with helpers bound to req
+// (e.g. csrf_field)
+func (r *HTMLRenderer) RenderHTMLWithRequest(w http.ResponseWriter, req *http.Request, templateName string, data interface{}) error {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
+	set, _ := r.sets.Get().(*renderSet)
+	if set == nil {
+		var err error
+		if set, err = r.newRenderSet(); err != nil {
+			return err
+		}
+	}
+	set.req, set.bound = req, nil
+	defer func() {
+		set.req, set.bound = nil, nil
+		r.sets.Put(set)
+	}()
+	templates := set.templates
+
 	// Try multiple template name formats
 	names := []string{
 		templateName,                // home/index.html
@@ -69,15 +236,15 @@ func (r *HTMLRenderer) RenderHTML(w http.ResponseWriter, templateName string, da
 		filepath.Base(filepath.Dir(templateName)) + "/" + filepath.Base(templateName), // home/index.html
 	}
 
-	var err error
 	var renderedName string
 
 	// Capture output to a buffer first (for hot reload injection)
 	var buf bytes.Buffer
+	var err error
 
 	for _, name := range names {
 		buf.Reset()
-		err = r.templates.ExecuteTemplate(&buf, name, data)
+		err = templates.ExecuteTemplate(&buf, name, data)
 		if err == nil {
 			renderedName = name
 			break
@@ -11,6 +11,10 @@ import (
 // MuxRouter implements Router interface
 type MuxRouter struct {
 	*mux.Router
+
+	// Wrap, when set, wraps every handler registered through the router,
+	// e.g. with the middleware of a route group
+	Wrap func(http.Handler) http.Handler
 }
 
 func NewMuxRouter() *MuxRouter {
@@ -19,6 +23,14 @@ func NewMuxRouter() *MuxRouter {
 	}
 }
 
+// HandleFunc registers a handler for path, wrapped with Wrap
+func (r *MuxRouter) HandleFunc(path string, handler func(http.ResponseWriter, *http.Request)) *mux.Route {
+	if r.Wrap == nil {
+		return r.Router.HandleFunc(path, handler)
+	}
+	return r.Router.Handle(path, r.Wrap(http.HandlerFunc(handler)))
+}
+
 func (r *MuxRouter) GET(path string, handler http.HandlerFunc) core.NamedRoute {
 	return &routing.NamedRoute{Route: r.HandleFunc(path, handler).Methods("GET")}
 }
@@ -0,0 +1,296 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+)
+
+// APIKeyPrefix starts every API key (rbl_<id>_<secret>) so leaked keys are
+// easy to spot
+const APIKeyPrefix = "rbl_"
+
+// ErrInvalidAPIKey is reported for unknown, revoked or malformed API keys
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// lastUsedResolution limits how often last_used_at is written for busy keys
+const lastUsedResolution = time.Minute
+
+var keyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+var validTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// APIKey is a stored API key. The secret part is only known when the key
+// is created; the database keeps its SHA-256 hash.
+type APIKey struct {
+	ID         string
+	Name       string
+	Subject    string // ID of the user the key acts as
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt time.Time // Zero if never used
+}
+
+// APIKeyStore keeps hashed API keys in a database table:
+//
+//	CREATE TABLE api_keys (
+//		id VARCHAR(32) PRIMARY KEY,
+//		name VARCHAR(255) NOT NULL,
+//		subject VARCHAR(255) NOT NULL,
+//		key_hash VARCHAR(64) NOT NULL,
+//		scopes TEXT NOT NULL,
+//		created_at BIGINT NOT NULL,
+//		last_used_at BIGINT NOT NULL
+//	)
+type APIKeyStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewAPIKeyStore creates a store using the "api_keys" table, creating it
+// if needed
+// Usage: keys, err := auth.NewAPIKeyStore(app.DB())
+func NewAPIKeyStore(db *sql.DB) (*APIKeyStore, error) {
+	return NewAPIKeyStoreWithTable(db, "api_keys")
+}
+
+// NewAPIKeyStoreWithTable creates an API key store using table
+func NewAPIKeyStoreWithTable(db *sql.DB, table string) (*APIKeyStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("api key store requires a database connection")
+	}
+	if !validTableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid api key table name: %s", table)
+	}
+
+	s := &APIKeyStore{db: db, table: table}
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(32) PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	key_hash VARCHAR(64) NOT NULL,
+	scopes TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	last_used_at BIGINT NOT NULL
+)`, table)
+	if _, err := db.Exec(query); err != nil {
+		return nil, fmt.Errorf("failed to create api key table: %w", err)
+	}
+	return s, nil
+}
+
+// Create generates a new key for subject. The returned key is shown to the
+// user once; only its hash is stored.
+// Usage: key, apiKey, err := keys.Create(ctx, "CI deploys", userID, "deploy")
+func (s *APIKeyStore) Create(ctx context.Context, name, subject string, scopes ...string) (string, *APIKey, error) {
+	id, err := randomKeyPart(10)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomKeyPart(20)
+	if err != nil {
+		return "", nil, err
+	}
+
+	apiKey := &APIKey{
+		ID:        id,
+		Name:      name,
+		Subject:   subject,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+
+	query := adapters.Rebind(s.db, fmt.Sprintf(
+		"INSERT INTO %s (id, name, subject, key_hash, scopes, created_at, last_used_at) VALUES (?, ?, ?, ?, ?, ?, ?)", s.table))
+	_, err = s.db.ExecContext(ctx, query, id, name, subject, hashKeySecret(secret),
+		strings.Join(scopes, " "), apiKey.CreatedAt.Unix(), 0)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to store api key: %w", err)
+	}
+
+	return APIKeyPrefix + id + "_" + secret, apiKey, nil
+}
+
+// Authenticate returns the stored key matching key and records its use
+func (s *APIKeyStore) Authenticate(ctx context.Context, key string) (*APIKey, error) {
+	id, secret, ok := splitAPIKey(key)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, hash, err := s.find(ctx, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashKeySecret(secret))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if now.Sub(apiKey.LastUsedAt) >= lastUsedResolution {
+		query := adapters.Rebind(s.db, fmt.Sprintf("UPDATE %s SET last_used_at = ? WHERE id = ?", s.table))
+		if _, err := s.db.ExecContext(ctx, query, now.Unix(), id); err != nil {
+			return nil, err
+		}
+		apiKey.LastUsedAt = now
+	}
+	return apiKey, nil
+}
+
+// Get returns the key with id
+func (s *APIKeyStore) Get(ctx context.Context, id string) (*APIKey, error) {
+	apiKey, _, err := s.find(ctx, id)
+	return apiKey, err
+}
+
+// List returns the keys of subject, or every key when subject is ""
+func (s *APIKeyStore) List(ctx context.Context, subject string) ([]*APIKey, error) {
+	query := fmt.Sprintf("SELECT id, name, subject, scopes, created_at, last_used_at FROM %s", s.table)
+	var args []interface{}
+	if subject != "" {
+		query += " WHERE subject = ?"
+		args = append(args, subject)
+	}
+	query += " ORDER BY created_at"
+
+	rows, err := s.db.QueryContext(ctx, adapters.Rebind(s.db, query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		var apiKey APIKey
+		var scopes string
+		var createdAt, lastUsedAt int64
+		if err := rows.Scan(&apiKey.ID, &apiKey.Name, &apiKey.Subject, &scopes, &createdAt, &lastUsedAt); err != nil {
+			return nil, err
+		}
+		apiKey.Scopes = strings.Fields(scopes)
+		apiKey.CreatedAt = time.Unix(createdAt, 0)
+		if lastUsedAt > 0 {
+			apiKey.LastUsedAt = time.Unix(lastUsedAt, 0)
+		}
+		keys = append(keys, &apiKey)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke deletes the key with id
+func (s *APIKeyStore) Revoke(ctx context.Context, id string) error {
+	query := adapters.Rebind(s.db, fmt.Sprintf("DELETE FROM %s WHERE id = ?", s.table))
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (s *APIKeyStore) find(ctx context.Context, id string) (*APIKey, string, error) {
+	query := adapters.Rebind(s.db, fmt.Sprintf(
+		"SELECT id, name, subject, key_hash, scopes, created_at, last_used_at FROM %s WHERE id = ?", s.table))
+
+	var apiKey APIKey
+	var hash, scopes string
+	var createdAt, lastUsedAt int64
+	err := s.db.QueryRowContext(ctx, query, id).
+		Scan(&apiKey.ID, &apiKey.Name, &apiKey.Subject, &hash, &scopes, &createdAt, &lastUsedAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	apiKey.Scopes = strings.Fields(scopes)
+	apiKey.CreatedAt = time.Unix(createdAt, 0)
+	if lastUsedAt > 0 {
+		apiKey.LastUsedAt = time.Unix(lastUsedAt, 0)
+	}
+	return &apiKey, hash, nil
+}
+
+// APIKeyAuth is a middleware that authenticates API keys sent in the
+// X-API-Key header or as "Authorization: Bearer rbl_...". Requests without
+// a key pass through; invalid keys get a 401.
+// Usage: app.Use(auth.APIKeyAuth(keys, models.FindUser))
+func APIKeyAuth(store *APIKeyStore, findUser UserFinder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("X-API-Key")
+			if key == "" {
+				if token := bearerToken(r); strings.HasPrefix(token, APIKeyPrefix) {
+					key = token
+				}
+			}
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			apiKey, err := store.Authenticate(r.Context(), key)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				writeJSONError(w, http.StatusUnauthorized, ErrInvalidAPIKey.Error())
+				return
+			}
+
+			r, err = authenticated(r, &Principal{
+				Subject: apiKey.Subject,
+				Method:  MethodAPIKey,
+				Scopes:  apiKey.Scopes,
+				KeyID:   apiKey.ID,
+			}, findUser)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				writeJSONError(w, http.StatusUnauthorized, ErrInvalidAPIKey.Error())
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// splitAPIKey parses rbl_<id>_<secret>
+func splitAPIKey(key string) (id, secret string, ok bool) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return "", "", false
+	}
+	id, secret, ok = strings.Cut(strings.TrimPrefix(key, APIKeyPrefix), "_")
+	return id, secret, ok && id != "" && secret != ""
+}
+
+// hashKeySecret hashes the random part of a key. Keys have enough entropy
+// that a fast hash is safe, unlike passwords.
+func hashKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomKeyPart(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(keyEncoding.EncodeToString(b)), nil
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
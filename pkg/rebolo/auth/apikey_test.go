@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openTestDB opens an empty SQLite database removed after the test
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestNewAPIKeyStore(t *testing.T) {
+	db := openTestDB(t)
+	tests := []struct {
+		name    string
+		db      *sql.DB
+		table   string
+		wantErr bool
+	}{
+		{"default table", db, "api_keys", false},
+		{"existing table", db, "api_keys", false},
+		{"no database", nil, "api_keys", true},
+		{"invalid table", db, "keys; DROP TABLE users", true},
+	}
+	for _, tt := range tests {
+		if _, err := NewAPIKeyStoreWithTable(tt.db, tt.table); (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAPIKeyStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewAPIKeyStore(openTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, created, err := store.Create(ctx, "CI deploys", "42", "deploy", "posts:read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := store.Create(ctx, "Other user", "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, APIKeyPrefix+created.ID+"_") {
+		t.Errorf("key %s doesn't start with %s%s_", key, APIKeyPrefix, created.ID)
+	}
+
+	id, secret, _ := splitAPIKey(key)
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{"valid", key, false},
+		{"wrong secret", APIKeyPrefix + id + "_" + strings.Repeat("a", len(secret)), true},
+		{"unknown id", APIKeyPrefix + "unknown_" + secret, true},
+		{"secret of another key", APIKeyPrefix + id + "_" + strings.SplitN(other, "_", 3)[2], true},
+		{"no prefix", strings.TrimPrefix(key, APIKeyPrefix), true},
+		{"no secret", APIKeyPrefix + id + "_", true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiKey, err := store.Authenticate(ctx, tt.key)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidAPIKey) {
+					t.Errorf("Authenticate() error = %v, want %v", err, ErrInvalidAPIKey)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if apiKey.Subject != "42" || strings.Join(apiKey.Scopes, " ") != "deploy posts:read" || apiKey.LastUsedAt.IsZero() {
+				t.Errorf("key = %+v, want subject 42 with its scopes, just used", apiKey)
+			}
+		})
+	}
+
+	keys, err := store.List(ctx, "42")
+	if err != nil || len(keys) != 1 || keys[0].Name != "CI deploys" {
+		t.Errorf("List(42) = %v (%v), want the CI deploys key", keys, err)
+	}
+	if keys, _ := store.List(ctx, ""); len(keys) != 2 {
+		t.Errorf("List() returned %d keys, want 2", len(keys))
+	}
+
+	if err := store.Revoke(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Authenticate(ctx, key); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("revoked key: error = %v, want %v", err, ErrInvalidAPIKey)
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	store, err := NewAPIKeyStore(openTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _, err := store.Create(context.Background(), "test", "42", "posts:read")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := APIKeyAuth(store, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal := PrincipalFrom(r); principal != nil && principal.Method == MethodAPIKey {
+			w.Write([]byte(principal.Subject))
+		}
+	}))
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"no key", "", "", http.StatusOK, ""},
+		{"X-API-Key", "X-API-Key", key, http.StatusOK, "42"},
+		{"bearer", "Authorization", "Bearer " + key, http.StatusOK, "42"},
+		{"bearer JWT", "Authorization", "Bearer a.b.c", http.StatusOK, ""},
+		{"invalid key", "X-API-Key", key + "x", http.StatusUnauthorized, "invalid API key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("got %d %q, want %d %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
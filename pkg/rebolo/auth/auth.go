@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/session"
+)
+
+// ErrUnauthenticated is reported when a request requires a logged in user
+var ErrUnauthenticated = errors.New("authentication required")
+
+// UserFinder loads a user by the ID stored in the session
+type UserFinder func(ctx context.Context, id string) (interface{}, error)
+
+// Config configures an Authenticator
+type Config struct {
+	SessionKey  string // Session key holding the user ID (default: user_id)
+	LoginPath   string // Where RequireLogin redirects HTML requests (default: /login)
+	ReturnToKey string // Session key remembering the page to return to (default: return_to)
+
+	// GetSession loads the session (default: session.GetSession)
+	GetSession func(r *http.Request, w http.ResponseWriter) (*session.Session, error)
+
+	// FindUser loads the current user for LoadUser and ctx.CurrentUser()
+	FindUser UserFinder
+}
+
+// Authenticator logs users in and out and protects routes
+type Authenticator struct {
+	config Config
+}
+
+// New creates an Authenticator
+func New(config Config) *Authenticator {
+	if config.SessionKey == "" {
+		config.SessionKey = "user_id"
+	}
+	if config.LoginPath == "" {
+		config.LoginPath = "/login"
+	}
+	if config.ReturnToKey == "" {
+		config.ReturnToKey = "return_to"
+	}
+	if config.GetSession == nil {
+		config.GetSession = session.GetSession
+	}
+	return &Authenticator{config: config}
+}
+
+// SetUserFinder sets the function used to load the current user
+func (a *Authenticator) SetUserFinder(fn UserFinder) {
+	a.config.FindUser = fn
+}
+
+// LookupUser loads a user with the configured UserFinder. It returns nil
+// when no finder is set.
+func (a *Authenticator) LookupUser(ctx context.Context, id string) (interface{}, error) {
+	if a.config.FindUser == nil {
+		return nil, nil
+	}
+	return a.config.FindUser(ctx, id)
+}
+
+// LoginPath returns the path RequireLogin redirects to
+func (a *Authenticator) LoginPath() string {
+	return a.config.LoginPath
+}
+
+// Login stores the user ID in a new session (the session ID is rotated to
+// prevent session fixation)
+func (a *Authenticator) Login(w http.ResponseWriter, r *http.Request, userID interface{}) error {
+	sess, err := a.config.GetSession(r, w)
+	if err != nil {
+		return err
+	}
+
+	sess.Delete(twoFactorPendingKey)
+	sess.Delete(twoFactorPendingAtKey)
+	sess.Delete(twoFactorVerifiedKey)
+	sess.Set(a.config.SessionKey, fmt.Sprint(userID))
+	return sess.Regenerate()
+}
+
+// Logout removes the user from the session
+func (a *Authenticator) Logout(w http.ResponseWriter, r *http.Request) error {
+	sess, err := a.config.GetSession(r, w)
+	if err != nil {
+		return err
+	}
+
+	sess.Delete(a.config.SessionKey)
+	sess.Delete(twoFactorVerifiedKey)
+	return sess.Regenerate()
+}
+
+// UserID returns the ID of the logged in user, or "" if none
+func (a *Authenticator) UserID(r *http.Request) string {
+	if id := UserIDFrom(r); id != "" {
+		return id
+	}
+
+	sess, err := a.config.GetSession(r, nil)
+	if err != nil {
+		return ""
+	}
+	return sess.GetString(a.config.SessionKey)
+}
+
+// ReturnTo returns (and forgets) the page RequireLogin redirected from, or
+// fallback. Use it after a successful login.
+func (a *Authenticator) ReturnTo(w http.ResponseWriter, r *http.Request, fallback string) string {
+	sess, err := a.config.GetSession(r, w)
+	if err != nil {
+		return fallback
+	}
+
+	returnTo := sess.GetString(a.config.ReturnToKey)
+	if returnTo == "" || !isLocalPath(returnTo) {
+		return fallback
+	}
+	sess.Delete(a.config.ReturnToKey)
+	sess.Save()
+	return returnTo
+}
+
+// LoadUser is a middleware that loads the logged in user into the request
+// context, making it available through UserFrom and ctx.CurrentUser()
+func (a *Authenticator) LoadUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess, err := a.config.GetSession(r, w)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id := sess.GetString(a.config.SessionKey)
+		if id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var user interface{}
+		if a.config.FindUser != nil {
+			user, err = a.config.FindUser(r.Context(), id)
+			if err != nil || user == nil {
+				// The user no longer exists, forget it
+				sess.Delete(a.config.SessionKey)
+				sess.Save()
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, WithUser(r, id, user))
+	})
+}
+
+// RequireLogin is a middleware that only lets logged in users through. HTML
+// requests are redirected to the login page and JSON requests get a 401.
+func (a *Authenticator) RequireLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.UserID(r) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if wantsJSON(r) {
+			writeJSONError(w, http.StatusUnauthorized, ErrUnauthenticated.Error())
+			return
+		}
+
+		// Come back here after logging in
+		if r.Method == http.MethodGet {
+			if sess, err := a.config.GetSession(r, w); err == nil {
+				sess.Set(a.config.ReturnToKey, r.URL.RequestURI())
+				sess.Save()
+			}
+		}
+
+		http.Redirect(w, r, a.config.LoginPath, http.StatusSeeOther)
+	})
+}
+
+type userContextKey struct{}
+
+// currentUser is stored in the request context by LoadUser
+type currentUser struct {
+	id   string
+	user interface{}
+}
+
+// WithUser returns a copy of r carrying the logged in user
+func WithUser(r *http.Request, id string, user interface{}) *http.Request {
+	ctx := context.WithValue(r.Context(), userContextKey{}, &currentUser{id: id, user: user})
+	return r.WithContext(ctx)
+}
+
+// UserFrom returns the user loaded by LoadUser, or nil
+func UserFrom(r *http.Request) interface{} {
+	if r == nil {
+		return nil
+	}
+	if current, ok := r.Context().Value(userContextKey{}).(*currentUser); ok {
+		return current.user
+	}
+	return nil
+}
+
+// UserIDFrom returns the ID of the user loaded by LoadUser, or ""
+func UserIDFrom(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	if current, ok := r.Context().Value(userContextKey{}).(*currentUser); ok {
+		return current.id
+	}
+	return ""
+}
+
+// Template helpers: {{if logged_in}} and {{current_user}}
+func init() {
+	adapters.RegisterTemplateHelper("current_user", func(r *http.Request) interface{} {
+		return func() interface{} { return UserFrom(r) }
+	})
+	adapters.RegisterTemplateHelper("logged_in", func(r *http.Request) interface{} {
+		return func() bool { return UserIDFrom(r) != "" }
+	})
+}
+
+// isLocalPath prevents open redirects through return_to
+func isLocalPath(path string) bool {
+	u, err := url.Parse(path)
+	return err == nil && u.Scheme == "" && u.Host == "" &&
+		strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//")
+}
+
+// wantsJSON reports whether the client is an AJAX or API request
+func wantsJSON(r *http.Request) bool {
+	return r.Header.Get("X-Requested-With") == "XMLHttpRequest" ||
+		strings.Contains(r.Header.Get("Accept"), "application/json") ||
+		strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+}
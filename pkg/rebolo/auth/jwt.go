@@ -0,0 +1,432 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// JWT signing algorithms
+const (
+	JWTAlgorithmHS256 = "HS256" // HMAC-SHA256 with a shared secret (default)
+	JWTAlgorithmEdDSA = "EdDSA" // Ed25519 key pair
+)
+
+// Token uses stored in the token_use claim
+const (
+	TokenUseAccess  = "access"
+	TokenUseRefresh = "refresh"
+)
+
+var (
+	// ErrInvalidToken is reported for malformed tokens or bad signatures
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenExpired is reported for tokens past their exp claim
+	ErrTokenExpired = errors.New("token expired")
+	// ErrTokenReused is reported for refresh tokens already exchanged
+	ErrTokenReused = errors.New("refresh token already used")
+)
+
+// JWTConfig configures token signing and verification
+type JWTConfig struct {
+	Algorithm  string             // HS256 (default) or EdDSA
+	Secret     []byte             // HS256 secret, at least 32 bytes
+	PrivateKey ed25519.PrivateKey // EdDSA signing key
+	PublicKey  ed25519.PublicKey  // EdDSA verification key (derived from PrivateKey if empty)
+	Issuer     string             // iss claim, checked when set
+	Audience   string             // aud claim, checked when set
+	TTL        time.Duration      // Access token lifetime (default: 15m)
+	RefreshTTL time.Duration      // Refresh token lifetime (default: 7 days)
+	Leeway     time.Duration      // Allowed clock skew when checking exp and nbf
+
+	// IsRevoked rejects otherwise valid tokens, e.g. refresh tokens whose
+	// jti was stored as revoked on logout
+	IsRevoked func(claims *Claims) bool
+
+	// RefreshTokens makes refresh tokens single-use (default: in memory,
+	// lost on restart). Revoke one on logout by consuming it.
+	RefreshTokens RefreshTokenStore
+}
+
+// Claims are the registered JWT claims used by rebolo tokens
+type Claims struct {
+	Subject   string `json:"sub,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ID        string `json:"jti,omitempty"`
+	Scope     string `json:"scope,omitempty"`     // Space separated scopes
+	TokenUse  string `json:"token_use,omitempty"` // access or refresh
+}
+
+// Scopes returns the scopes of the scope claim
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// UnmarshalJSON also accepts aud as an array, as allowed by RFC 7519
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	type plain Claims
+	var raw struct {
+		plain
+		Audience json.RawMessage `json:"aud,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*c = Claims(raw.plain)
+
+	if len(raw.Audience) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw.Audience, &c.Audience); err == nil {
+		return nil
+	}
+	var audiences []string
+	if err := json.Unmarshal(raw.Audience, &audiences); err != nil {
+		return err
+	}
+	c.Audience = strings.Join(audiences, " ")
+	return nil
+}
+
+// TokenPair is returned when issuing or refreshing tokens, in the shape of
+// an OAuth2 token response
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// JWT signs and verifies bearer tokens
+type JWT struct {
+	config JWTConfig
+}
+
+// NewJWT creates a token signer
+// Usage: tokens, err := auth.NewJWT(auth.JWTConfig{Secret: []byte(os.Getenv("JWT_SECRET"))})
+func NewJWT(config JWTConfig) (*JWT, error) {
+	if config.Algorithm == "" {
+		config.Algorithm = JWTAlgorithmHS256
+	}
+	if config.TTL == 0 {
+		config.TTL = 15 * time.Minute
+	}
+	if config.RefreshTTL == 0 {
+		config.RefreshTTL = 7 * 24 * time.Hour
+	}
+	if config.RefreshTokens == nil {
+		config.RefreshTokens = NewMemoryRefreshTokenStore()
+	}
+
+	switch config.Algorithm {
+	case JWTAlgorithmHS256:
+		if len(config.Secret) < 32 {
+			return nil, fmt.Errorf("jwt: HS256 secret must be at least 32 bytes")
+		}
+	case JWTAlgorithmEdDSA:
+		if len(config.PublicKey) == 0 && len(config.PrivateKey) == ed25519.PrivateKeySize {
+			config.PublicKey = config.PrivateKey.Public().(ed25519.PublicKey)
+		}
+		if len(config.PublicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwt: EdDSA requires an Ed25519 key")
+		}
+	default:
+		return nil, fmt.Errorf("jwt: unsupported algorithm %q", config.Algorithm)
+	}
+
+	return &JWT{config: config}, nil
+}
+
+// Sign returns a signed token for claims, filling in iat, exp (TTL), iss,
+// aud and jti when empty
+func (j *JWT) Sign(claims Claims) (string, error) {
+	if claims.IssuedAt == 0 {
+		claims.IssuedAt = time.Now().Unix()
+	}
+	if claims.ExpiresAt == 0 {
+		claims.ExpiresAt = time.Unix(claims.IssuedAt, 0).Add(j.config.TTL).Unix()
+	}
+	if claims.Issuer == "" {
+		claims.Issuer = j.config.Issuer
+	}
+	if claims.Audience == "" {
+		claims.Audience = j.config.Audience
+	}
+	if claims.ID == "" {
+		id, err := tokenID()
+		if err != nil {
+			return "", err
+		}
+		claims.ID = id
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": j.config.Algorithm, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := j.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Issue returns an access and refresh token pair for subject
+// Usage: pair, err := app.JWT().Issue(userID, "posts:read")
+func (j *JWT) Issue(subject string, scopes ...string) (*TokenPair, error) {
+	now := time.Now()
+	scope := strings.Join(scopes, " ")
+
+	access, err := j.Sign(Claims{
+		Subject:   subject,
+		Scope:     scope,
+		TokenUse:  TokenUseAccess,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(j.config.TTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshID, err := tokenID()
+	if err != nil {
+		return nil, err
+	}
+	refreshExpires := now.Add(j.config.RefreshTTL)
+	if err := j.config.RefreshTokens.Save(context.Background(), refreshID, subject, refreshExpires); err != nil {
+		return nil, err
+	}
+	refresh, err := j.Sign(Claims{
+		Subject:   subject,
+		Scope:     scope,
+		TokenUse:  TokenUseRefresh,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: refreshExpires.Unix(),
+		ID:        refreshID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(j.config.TTL / time.Second),
+	}, nil
+}
+
+// Refresh verifies a refresh token and issues a new pair with the same
+// subject and scopes. Each refresh token is accepted once, a second use
+// gets ErrTokenReused.
+func (j *JWT) Refresh(refreshToken string) (*TokenPair, error) {
+	claims, err := j.Verify(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenUse != TokenUseRefresh {
+		return nil, fmt.Errorf("%w: not a refresh token", ErrInvalidToken)
+	}
+	if err := j.RevokeRefreshToken(claims); err != nil {
+		return nil, err
+	}
+	return j.Issue(claims.Subject, claims.Scopes()...)
+}
+
+// RevokeRefreshToken consumes a verified refresh token, e.g. on logout
+// Usage: claims, err := app.JWT().Verify(token); err = app.JWT().RevokeRefreshToken(claims)
+func (j *JWT) RevokeRefreshToken(claims *Claims) error {
+	valid, err := j.config.RefreshTokens.Consume(context.Background(), claims.ID)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrTokenReused
+	}
+	return nil
+}
+
+// Verify checks the signature and the exp, nbf, iss and aud claims of
+// token and returns its claims. Tokens without exp are rejected.
+func (j *JWT) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	// Only the configured algorithm is accepted, never the token's choice
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.Alg != j.config.Algorithm {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !j.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if claims.ExpiresAt == 0 {
+		return nil, fmt.Errorf("%w: no expiry", ErrInvalidToken)
+	}
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(j.config.Leeway)) {
+		return nil, ErrTokenExpired
+	}
+	if claims.NotBefore != 0 && now.Add(j.config.Leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+	if j.config.Issuer != "" && claims.Issuer != j.config.Issuer {
+		return nil, fmt.Errorf("%w: wrong issuer", ErrInvalidToken)
+	}
+	if j.config.Audience != "" && !containsField(claims.Audience, j.config.Audience) {
+		return nil, fmt.Errorf("%w: wrong audience", ErrInvalidToken)
+	}
+	if j.config.IsRevoked != nil && j.config.IsRevoked(&claims) {
+		return nil, fmt.Errorf("%w: revoked", ErrInvalidToken)
+	}
+
+	return &claims, nil
+}
+
+func (j *JWT) sign(input []byte) ([]byte, error) {
+	switch j.config.Algorithm {
+	case JWTAlgorithmEdDSA:
+		if len(j.config.PrivateKey) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("jwt: no Ed25519 private key to sign with")
+		}
+		return ed25519.Sign(j.config.PrivateKey, input), nil
+	default:
+		mac := hmac.New(sha256.New, j.config.Secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	}
+}
+
+func (j *JWT) verify(input, signature []byte) bool {
+	switch j.config.Algorithm {
+	case JWTAlgorithmEdDSA:
+		return ed25519.Verify(j.config.PublicKey, input, signature)
+	default:
+		mac := hmac.New(sha256.New, j.config.Secret)
+		mac.Write(input)
+		return hmac.Equal(mac.Sum(nil), signature)
+	}
+}
+
+// BearerAuth is a middleware that authenticates access tokens sent as
+// "Authorization: Bearer <jwt>". Requests without a token pass through;
+// invalid or expired tokens get a 401.
+// Usage: app.Use(auth.BearerAuth(tokens, models.FindUser))
+func BearerAuth(j *JWT, findUser UserFinder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := bearerToken(r)
+			// API keys use the same header and are handled by APIKeyAuth
+			if token == "" || strings.Count(token, ".") != 2 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := j.Verify(token)
+			if err == nil && claims.TokenUse == TokenUseRefresh {
+				err = fmt.Errorf("%w: refresh tokens can't be used for requests", ErrInvalidToken)
+			}
+			if err == nil {
+				r, err = authenticated(r, &Principal{
+					Subject: claims.Subject,
+					Method:  MethodJWT,
+					Scopes:  claims.Scopes(),
+					Claims:  claims,
+				}, findUser)
+			}
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				message := ErrInvalidToken.Error()
+				if errors.Is(err, ErrTokenExpired) {
+					message = ErrTokenExpired.Error()
+				}
+				writeJSONError(w, http.StatusUnauthorized, message)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LoadEd25519PrivateKey reads a PEM encoded (PKCS8) Ed25519 private key,
+// e.g. generated with: openssl genpkey -algorithm ed25519 -out jwt.pem
+func LoadEd25519PrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("jwt: no PEM data in %s", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: failed to parse %s: %w", path, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("jwt: %s is not an Ed25519 key", path)
+	}
+	return privateKey, nil
+}
+
+func tokenID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func containsField(list, value string) bool {
+	for _, field := range strings.Fields(list) {
+		if field == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// signRaw signs a token with any header and payload, to build tokens Sign
+// would never produce
+func signRaw(t *testing.T, j *JWT, header, payload interface{}) string {
+	t.Helper()
+	headerJSON, _ := json.Marshal(header)
+	payloadJSON, _ := json.Marshal(payload)
+	input := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payloadJSON)
+	signature, err := j.sign([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestNewJWT(t *testing.T) {
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name    string
+		config  JWTConfig
+		wantErr bool
+	}{
+		{"HS256", JWTConfig{Secret: testSecret}, false},
+		{"short secret", JWTConfig{Secret: []byte("secret")}, true},
+		{"EdDSA", JWTConfig{Algorithm: JWTAlgorithmEdDSA, PrivateKey: privateKey}, false},
+		{"EdDSA verify only", JWTConfig{Algorithm: JWTAlgorithmEdDSA, PublicKey: privateKey.Public().(ed25519.PublicKey)}, false},
+		{"EdDSA without key", JWTConfig{Algorithm: JWTAlgorithmEdDSA}, true},
+		{"unsupported algorithm", JWTConfig{Algorithm: "RS256", Secret: testSecret}, true},
+	}
+	for _, tt := range tests {
+		if _, err := NewJWT(tt.config); (err != nil) != tt.wantErr {
+			t.Errorf("%s: NewJWT() error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestJWTVerify(t *testing.T) {
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	config := JWTConfig{Secret: testSecret, Issuer: "rebolo", Audience: "api", Leeway: 5 * time.Second}
+	j, err := NewJWT(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := NewJWT(JWTConfig{Secret: []byte("another secret, also 32 bytes ok"), Issuer: "rebolo", Audience: "api"})
+	eddsa, _ := NewJWT(JWTConfig{Algorithm: JWTAlgorithmEdDSA, PrivateKey: privateKey, Issuer: "rebolo", Audience: "api"})
+
+	now := time.Now()
+	sign := func(signer *JWT, claims Claims) string {
+		token, err := signer.Sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := sign(j, Claims{Subject: "42", Scope: "posts:read posts:write"})
+	parts := strings.Split(valid, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"valid", valid, nil},
+		{"within leeway", sign(j, Claims{Subject: "42", ExpiresAt: now.Add(-2 * time.Second).Unix()}), nil},
+		{"audience in array", signRaw(t, j, map[string]string{"alg": "HS256"}, map[string]interface{}{
+			"sub": "42", "iss": "rebolo", "aud": []string{"web", "api"}, "exp": now.Add(time.Minute).Unix(),
+		}), nil},
+		{"expired", sign(j, Claims{Subject: "42", ExpiresAt: now.Add(-time.Minute).Unix()}), ErrTokenExpired},
+		{"no expiry", signRaw(t, j, map[string]string{"alg": "HS256"}, map[string]interface{}{
+			"sub": "42", "iss": "rebolo", "aud": "api",
+		}), ErrInvalidToken},
+		{"not valid yet", sign(j, Claims{Subject: "42", NotBefore: now.Add(time.Minute).Unix()}), ErrInvalidToken},
+		{"wrong issuer", sign(j, Claims{Subject: "42", Issuer: "other"}), ErrInvalidToken},
+		{"wrong audience", sign(j, Claims{Subject: "42", Audience: "web"}), ErrInvalidToken},
+		{"other secret", sign(other, Claims{Subject: "42"}), ErrInvalidToken},
+		{"other algorithm", sign(eddsa, Claims{Subject: "42"}), ErrInvalidToken},
+		{"alg none", base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + ".", ErrInvalidToken},
+		{"changed payload", parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"1"}`)) + "." + parts[2], ErrInvalidToken},
+		{"malformed", "abc.def", ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := j.Verify(tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if claims.Subject != "42" || claims.ExpiresAt == 0 {
+				t.Errorf("claims = %+v, want sub 42 with exp", claims)
+			}
+		})
+	}
+
+	// Sign fills in the claims left empty
+	claims, _ := j.Verify(valid)
+	if claims.ID == "" || claims.IssuedAt == 0 || claims.Issuer != "rebolo" || claims.Audience != "api" {
+		t.Errorf("claims = %+v, want jti, iat, iss and aud set", claims)
+	}
+	if scopes := claims.Scopes(); len(scopes) != 2 || scopes[1] != "posts:write" {
+		t.Errorf("Scopes() = %v, want [posts:read posts:write]", scopes)
+	}
+}
+
+func TestJWTEdDSA(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := NewJWT(JWTConfig{Algorithm: JWTAlgorithmEdDSA, PrivateKey: privateKey})
+	verifier, _ := NewJWT(JWTConfig{Algorithm: JWTAlgorithmEdDSA, PublicKey: publicKey})
+
+	token, err := signer.Sign(Claims{Subject: "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(token); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if _, err := verifier.Sign(Claims{Subject: "42"}); err == nil {
+		t.Error("Sign() without private key succeeded")
+	}
+}
+
+func TestJWTRefresh(t *testing.T) {
+	j, _ := NewJWT(JWTConfig{Secret: testSecret})
+	pair, err := j.Issue("42", "posts:read")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := j.Refresh(pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("refreshing with an access token: error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	refreshed, err := j.Refresh(pair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	claims, err := j.Verify(refreshed.AccessToken)
+	if err != nil || claims.Subject != "42" || claims.Scope != "posts:read" || claims.TokenUse != TokenUseAccess {
+		t.Errorf("refreshed access token claims = %+v (%v), want sub 42 with posts:read", claims, err)
+	}
+
+	// A stolen refresh token can't be used once its owner refreshed
+	if _, err := j.Refresh(pair.RefreshToken); !errors.Is(err, ErrTokenReused) {
+		t.Errorf("second refresh: error = %v, want %v", err, ErrTokenReused)
+	}
+
+	// Revoked on logout
+	claims, _ = j.Verify(refreshed.RefreshToken)
+	if err := j.RevokeRefreshToken(claims); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Refresh(refreshed.RefreshToken); !errors.Is(err, ErrTokenReused) {
+		t.Errorf("refresh after revoking: error = %v, want %v", err, ErrTokenReused)
+	}
+}
+
+func TestJWTConcurrentRefresh(t *testing.T) {
+	j, _ := NewJWT(JWTConfig{Secret: testSecret})
+	pair, err := j.Issue("42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := j.Refresh(pair.RefreshToken)
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d concurrent refreshes succeeded, want 1", succeeded)
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	j, _ := NewJWT(JWTConfig{Secret: testSecret})
+	pair, _ := j.Issue("42", "posts:read")
+	expired, _ := j.Sign(Claims{Subject: "42", TokenUse: TokenUseAccess, ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+
+	handler := BearerAuth(j, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal := PrincipalFrom(r); principal != nil {
+			w.Write([]byte(principal.Subject))
+		}
+	}))
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"no token", "", http.StatusOK, ""},
+		{"access token", pair.AccessToken, http.StatusOK, "42"},
+		{"API key", "rbl_abc", http.StatusOK, ""},
+		{"refresh token", pair.RefreshToken, http.StatusUnauthorized, "invalid token"},
+		{"expired", expired, http.StatusUnauthorized, "token expired"},
+		{"bad signature", pair.AccessToken + "x", http.StatusUnauthorized, "invalid token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("got %d %q, want %d %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,537 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	rerrors "github.com/Palaciodiego008/rebololang/pkg/rebolo/errors"
+	"github.com/gorilla/mux"
+)
+
+// Session keys holding the state of a login in progress
+const (
+	oauthProviderKey = "_oauth_provider"
+	oauthStateKey    = "_oauth_state"
+	oauthNonceKey    = "_oauth_nonce"
+	oauthVerifierKey = "_oauth_verifier"
+)
+
+// idTokenLeeway is the clock skew allowed when checking ID token expiry
+const idTokenLeeway = time.Minute
+
+// OAuthProvider configures an OAuth2 or OpenID Connect identity provider.
+// With an Issuer the endpoints are discovered from
+// {issuer}/.well-known/openid-configuration and ID tokens are verified
+// against the provider's JWKS.
+type OAuthProvider struct {
+	Name         string // Used in /auth/{name} and /auth/{name}/callback
+	ClientID     string
+	ClientSecret string // Empty for public clients (PKCE only)
+	Issuer       string // OpenID Connect issuer URL
+
+	// Endpoints, discovered from the Issuer when empty
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	JWKSURL     string
+
+	RedirectURL string   // Default: {scheme}://{host}/auth/{name}/callback
+	Scopes      []string // Default: openid email profile with an Issuer
+	HTTPClient  *http.Client
+
+	mu         sync.Mutex
+	discovered bool
+	keys       *keySet
+}
+
+// OAuthIdentity is the user returned by a provider after a successful login
+type OAuthIdentity struct {
+	Provider      string
+	Subject       string // Stable user ID at the provider (sub claim)
+	Email         string
+	EmailVerified bool
+	Name          string
+	Claims        map[string]interface{} // ID token claims merged with userinfo
+	AccessToken   string
+	RefreshToken  string
+	Expiry        time.Time
+	IDToken       string
+}
+
+// OAuthUserMapper returns the ID of the local user to log in for identity,
+// e.g. finding or creating a user by email. Return an HTTPError (e.g. 403)
+// to refuse the login.
+type OAuthUserMapper func(ctx context.Context, identity *OAuthIdentity) (interface{}, error)
+
+// OAuthConfig configures OAuth logins
+type OAuthConfig struct {
+	MapUser        OAuthUserMapper
+	AfterLoginPath string // Where to go when no page is waiting for the login (default: /)
+
+	// ErrorHandler renders failed logins (default: plain error with the
+	// HTTPError status, or 500)
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// OAuth runs "Sign in with ..." logins through the authorization code flow
+// with PKCE, storing state, nonce and code verifier in the session
+type OAuth struct {
+	authenticator *Authenticator
+	config        OAuthConfig
+	mu            sync.RWMutex
+	providers     map[string]*OAuthProvider
+}
+
+// NewOAuth creates an OAuth login handler logging users in through
+// authenticator
+func NewOAuth(authenticator *Authenticator, config OAuthConfig) *OAuth {
+	if config.AfterLoginPath == "" {
+		config.AfterLoginPath = "/"
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), rerrors.StatusCode(err))
+		}
+	}
+	return &OAuth{
+		authenticator: authenticator,
+		config:        config,
+		providers:     make(map[string]*OAuthProvider),
+	}
+}
+
+// AddProvider registers p under p.Name
+func (o *OAuth) AddProvider(p *OAuthProvider) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.providers[p.Name] = p
+}
+
+// Provider returns the provider registered as name
+func (o *OAuth) Provider(name string) (*OAuthProvider, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	p, ok := o.providers[name]
+	return p, ok
+}
+
+// LoginHandler redirects to the provider named by the {provider} route
+// variable
+// Usage: app.GET("/auth/{provider}", oauth.LoginHandler)
+func (o *OAuth) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	p, ok := o.Provider(mux.Vars(r)["provider"])
+	if !ok {
+		o.config.ErrorHandler(w, r, rerrors.NewHTTPError(http.StatusNotFound, fmt.Errorf("unknown login provider")))
+		return
+	}
+
+	authURL, err := o.begin(w, r, p)
+	if err != nil {
+		o.config.ErrorHandler(w, r, err)
+		return
+	}
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// CallbackHandler completes the login when the provider redirects back,
+// logs in the user returned by MapUser and redirects to the page that
+// required the login
+// Usage: app.GET("/auth/{provider}/callback", oauth.CallbackHandler)
+func (o *OAuth) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	p, ok := o.Provider(mux.Vars(r)["provider"])
+	if !ok {
+		o.config.ErrorHandler(w, r, rerrors.NewHTTPError(http.StatusNotFound, fmt.Errorf("unknown login provider")))
+		return
+	}
+
+	identity, err := o.complete(w, r, p)
+	if err != nil {
+		o.config.ErrorHandler(w, r, err)
+		return
+	}
+
+	if o.config.MapUser == nil {
+		o.config.ErrorHandler(w, r, fmt.Errorf("oauth: no user mapper configured"))
+		return
+	}
+	userID, err := o.config.MapUser(r.Context(), identity)
+	if err != nil {
+		o.config.ErrorHandler(w, r, err)
+		return
+	}
+
+	if err := o.authenticator.Login(w, r, userID); err != nil {
+		o.config.ErrorHandler(w, r, err)
+		return
+	}
+	http.Redirect(w, r, o.authenticator.ReturnTo(w, r, o.config.AfterLoginPath), http.StatusSeeOther)
+}
+
+// begin stores a new state, nonce and PKCE verifier in the session and
+// returns the provider's authorization URL
+func (o *OAuth) begin(w http.ResponseWriter, r *http.Request, p *OAuthProvider) (string, error) {
+	if err := p.discover(r.Context()); err != nil {
+		return "", err
+	}
+
+	state, nonce, verifier := randomURLToken(), randomURLToken(), randomURLToken()
+
+	sess, err := o.authenticator.config.GetSession(r, w)
+	if err != nil {
+		return "", err
+	}
+	sess.Set(oauthProviderKey, p.Name)
+	sess.Set(oauthStateKey, state)
+	sess.Set(oauthNonceKey, nonce)
+	sess.Set(oauthVerifierKey, verifier)
+	if err := sess.Save(); err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(p.AuthURL)
+	if err != nil {
+		return "", fmt.Errorf("oauth: invalid authorization URL: %w", err)
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.ClientID)
+	query.Set("redirect_uri", p.redirectURL(r))
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	if scopes := p.scopes(); len(scopes) > 0 {
+		query.Set("scope", strings.Join(scopes, " "))
+	}
+	if p.isOIDC() {
+		query.Set("nonce", nonce)
+	}
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), nil
+}
+
+// complete checks the state, exchanges the code and verifies the ID token
+func (o *OAuth) complete(w http.ResponseWriter, r *http.Request, p *OAuthProvider) (*OAuthIdentity, error) {
+	query := r.URL.Query()
+	if code := query.Get("error"); code != "" {
+		return nil, rerrors.NewHTTPError(http.StatusUnauthorized,
+			fmt.Errorf("login failed: %s %s", code, query.Get("error_description")))
+	}
+
+	sess, err := o.authenticator.config.GetSession(r, w)
+	if err != nil {
+		return nil, err
+	}
+	provider := sess.GetString(oauthProviderKey)
+	state := sess.GetString(oauthStateKey)
+	nonce := sess.GetString(oauthNonceKey)
+	verifier := sess.GetString(oauthVerifierKey)
+
+	// The state is single use
+	sess.Delete(oauthProviderKey)
+	sess.Delete(oauthStateKey)
+	sess.Delete(oauthNonceKey)
+	sess.Delete(oauthVerifierKey)
+	if err := sess.Save(); err != nil {
+		return nil, err
+	}
+
+	if state == "" || provider != p.Name ||
+		subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		return nil, rerrors.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("invalid login state, please try again"))
+	}
+
+	if err := p.discover(r.Context()); err != nil {
+		return nil, err
+	}
+
+	tokens, err := p.exchange(r.Context(), query.Get("code"), p.redirectURL(r), verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &OAuthIdentity{
+		Provider:     p.Name,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		IDToken:      tokens.IDToken,
+		Claims:       make(map[string]interface{}),
+	}
+	if tokens.ExpiresIn > 0 {
+		identity.Expiry = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second)
+	}
+
+	if tokens.IDToken != "" {
+		claims, err := p.verifyIDToken(r.Context(), tokens.IDToken, nonce)
+		if err != nil {
+			return nil, rerrors.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("invalid ID token: %w", err))
+		}
+		identity.Claims = claims
+	} else if p.isOIDC() {
+		return nil, rerrors.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("provider returned no ID token"))
+	}
+
+	if p.UserInfoURL != "" && tokens.AccessToken != "" {
+		info, err := p.userInfo(r.Context(), tokens.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		if sub, ok := identity.Claims["sub"]; ok && info["sub"] != sub {
+			return nil, rerrors.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("userinfo subject doesn't match the ID token"))
+		}
+		for k, v := range info {
+			if _, ok := identity.Claims[k]; !ok {
+				identity.Claims[k] = v
+			}
+		}
+	}
+
+	identity.Subject = claimString(identity.Claims, "sub")
+	if identity.Subject == "" {
+		// Plain OAuth2 providers often use id instead of sub
+		identity.Subject = claimString(identity.Claims, "id")
+	}
+	identity.Email = claimString(identity.Claims, "email")
+	identity.EmailVerified, _ = identity.Claims["email_verified"].(bool)
+	identity.Name = claimString(identity.Claims, "name")
+
+	if identity.Subject == "" {
+		return nil, rerrors.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("provider returned no user ID"))
+	}
+	return identity, nil
+}
+
+func (p *OAuthProvider) isOIDC() bool {
+	for _, scope := range p.scopes() {
+		if scope == "openid" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *OAuthProvider) scopes() []string {
+	if len(p.Scopes) == 0 && p.Issuer != "" {
+		return []string{"openid", "email", "profile"}
+	}
+	return p.Scopes
+}
+
+func (p *OAuthProvider) client() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+func (p *OAuthProvider) redirectURL(r *http.Request) string {
+	if p.RedirectURL != "" {
+		return p.RedirectURL
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/auth/" + url.PathEscape(p.Name) + "/callback"
+}
+
+// discover fills in the endpoints from the OpenID Connect discovery
+// document, once
+func (p *OAuthProvider) discover(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Issuer != "" && !p.discovered {
+		var doc struct {
+			Issuer                string `json:"issuer"`
+			AuthorizationEndpoint string `json:"authorization_endpoint"`
+			TokenEndpoint         string `json:"token_endpoint"`
+			UserInfoEndpoint      string `json:"userinfo_endpoint"`
+			JWKSURI               string `json:"jwks_uri"`
+		}
+		wellKnown := strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := p.getJSON(ctx, wellKnown, "", &doc); err != nil {
+			return fmt.Errorf("oauth: discovery failed for %s: %w", p.Name, err)
+		}
+		if strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(p.Issuer, "/") {
+			return fmt.Errorf("oauth: discovery for %s returned issuer %s", p.Name, doc.Issuer)
+		}
+
+		if p.AuthURL == "" {
+			p.AuthURL = doc.AuthorizationEndpoint
+		}
+		if p.TokenURL == "" {
+			p.TokenURL = doc.TokenEndpoint
+		}
+		if p.UserInfoURL == "" {
+			p.UserInfoURL = doc.UserInfoEndpoint
+		}
+		if p.JWKSURL == "" {
+			p.JWKSURL = doc.JWKSURI
+		}
+		p.discovered = true
+	}
+
+	if p.keys == nil && p.JWKSURL != "" {
+		p.keys = newKeySet(p.JWKSURL, p.client())
+	}
+	if p.AuthURL == "" || p.TokenURL == "" {
+		return fmt.Errorf("oauth: provider %s has no authorization or token URL", p.Name)
+	}
+	return nil
+}
+
+// tokenResponse is the token endpoint response (RFC 6749 section 5.1)
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (p *OAuthProvider) exchange(ctx context.Context, code, redirectURL, verifier string) (*tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"code_verifier": {verifier},
+	}
+	if p.ClientSecret == "" {
+		form.Set("client_id", p.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oauth: token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokens tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("oauth: invalid token response (%s): %w", resp.Status, err)
+	}
+	if tokens.Error != "" || resp.StatusCode != http.StatusOK {
+		return nil, rerrors.NewHTTPError(http.StatusUnauthorized,
+			fmt.Errorf("token exchange failed: %s %s", tokens.Error, tokens.ErrorDescription))
+	}
+	return &tokens, nil
+}
+
+// verifyIDToken checks the signature, issuer, audience, expiry and nonce of
+// an ID token and returns its claims
+func (p *OAuthProvider) verifyIDToken(ctx context.Context, token, nonce string) (map[string]interface{}, error) {
+	if p.keys == nil {
+		return nil, fmt.Errorf("no JWKS URL configured")
+	}
+	payload, err := p.keys.verifyJWS(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if p.Issuer != "" && strings.TrimSuffix(claimString(claims, "iss"), "/") != strings.TrimSuffix(p.Issuer, "/") {
+		return nil, fmt.Errorf("wrong issuer")
+	}
+
+	var audiences []string
+	switch aud := claims["aud"].(type) {
+	case string:
+		audiences = []string{aud}
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				audiences = append(audiences, s)
+			}
+		}
+	}
+	if !containsField(strings.Join(audiences, " "), p.ClientID) {
+		return nil, fmt.Errorf("wrong audience")
+	}
+	if len(audiences) > 1 && claimString(claims, "azp") != p.ClientID {
+		return nil, fmt.Errorf("wrong authorized party")
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Now().After(time.Unix(int64(exp), 0).Add(idTokenLeeway)) {
+		return nil, ErrTokenExpired
+	}
+
+	if nonce != "" && subtle.ConstantTimeCompare([]byte(claimString(claims, "nonce")), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("wrong nonce")
+	}
+	return claims, nil
+}
+
+func (p *OAuthProvider) userInfo(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	var info map[string]interface{}
+	if err := p.getJSON(ctx, p.UserInfoURL, accessToken, &info); err != nil {
+		return nil, fmt.Errorf("oauth: userinfo request failed: %w", err)
+	}
+	return info, nil
+}
+
+func (p *OAuthProvider) getJSON(ctx context.Context, endpoint, accessToken string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := p.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// claimString returns a claim as a string (numeric IDs are formatted)
+func claimString(claims map[string]interface{}, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
+
+func randomURLToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/session"
+	rtesting "github.com/Palaciodiego008/rebololang/pkg/rebolo/testing"
+	"github.com/gorilla/mux"
+)
+
+// oauthApp serves the login and callback routes of an OAuth with the "corp"
+// provider backed by a fake OpenID Connect provider
+type oauthApp struct {
+	idp        *rtesting.OIDCProvider
+	provider   *OAuthProvider
+	server     *httptest.Server
+	identities []*OAuthIdentity
+}
+
+func newOAuthApp(t *testing.T) *oauthApp {
+	t.Helper()
+
+	app := &oauthApp{idp: rtesting.NewOIDCProvider("client-id", "client-secret")}
+	t.Cleanup(app.idp.Close)
+
+	store := session.NewCookieSessionStore("test_session", session.KeyPairs("0123456789abcdef0123456789abcdef")...)
+	authenticator := New(Config{GetSession: store.Get})
+	oauth := NewOAuth(authenticator, OAuthConfig{
+		MapUser: func(ctx context.Context, identity *OAuthIdentity) (interface{}, error) {
+			app.identities = append(app.identities, identity)
+			return identity.Subject, nil
+		},
+	})
+	app.provider = &OAuthProvider{
+		Name:         "corp",
+		Issuer:       app.idp.Issuer(),
+		ClientID:     app.idp.ClientID,
+		ClientSecret: app.idp.ClientSecret,
+	}
+	oauth.AddProvider(app.provider)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/auth/{provider}", oauth.LoginHandler)
+	router.HandleFunc("/auth/{provider}/callback", oauth.CallbackHandler)
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("logged in as " + authenticator.UserID(r)))
+	})
+	app.server = httptest.NewServer(router)
+	t.Cleanup(app.server.Close)
+	return app
+}
+
+// client returns a browser with its own cookies that doesn't follow
+// redirects, so each step of the flow can be checked
+func (app *oauthApp) client() *http.Client {
+	jar, _ := cookiejar.New(nil)
+	return &http.Client{
+		Jar: jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// get requests target and returns the response status and Location
+func get(t *testing.T, client *http.Client, target string) (int, *url.URL) {
+	t.Helper()
+	res, err := client.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	location, _ := res.Location()
+	return res.StatusCode, location
+}
+
+// authorize starts a login and returns the provider's redirect back to the
+// app, carrying the code and state
+func (app *oauthApp) authorize(t *testing.T, client *http.Client) *url.URL {
+	t.Helper()
+	status, authURL := get(t, client, app.server.URL+"/auth/corp")
+	if status != http.StatusFound || authURL == nil {
+		t.Fatalf("login: got status %d, want a redirect to the provider", status)
+	}
+	status, callback := get(t, client, authURL.String())
+	if status != http.StatusFound || callback == nil || callback.Query().Get("code") == "" {
+		t.Fatalf("authorize: got status %d, redirect %v, want a code", status, callback)
+	}
+	return callback
+}
+
+func TestOAuthLogin(t *testing.T) {
+	app := newOAuthApp(t)
+	client := app.client()
+
+	status, authURL := get(t, client, app.server.URL+"/auth/corp")
+	if status != http.StatusFound {
+		t.Fatalf("login: got status %d, want 302", status)
+	}
+	query := authURL.Query()
+	for _, param := range []string{"state", "nonce", "code_challenge"} {
+		if query.Get(param) == "" {
+			t.Errorf("authorization URL has no %s: %s", param, authURL)
+		}
+	}
+	if method := query.Get("code_challenge_method"); method != "S256" {
+		t.Errorf("code_challenge_method = %q, want S256", method)
+	}
+	if challenge, err := base64.RawURLEncoding.DecodeString(query.Get("code_challenge")); err != nil || len(challenge) != 32 {
+		t.Errorf("code_challenge %q isn't a base64url SHA-256", query.Get("code_challenge"))
+	}
+
+	_, callback := get(t, client, authURL.String())
+	status, location := get(t, client, callback.String())
+	if status != http.StatusSeeOther || location.Path != "/" {
+		t.Fatalf("callback: got status %d, redirect %v, want 303 to /", status, location)
+	}
+	if len(app.identities) != 1 {
+		t.Fatalf("MapUser called %d times, want 1", len(app.identities))
+	}
+	identity := app.identities[0]
+	if identity.Subject != "1" || identity.Email != "user@example.com" || !identity.EmailVerified {
+		t.Errorf("identity = %+v, want the fake provider's user", identity)
+	}
+	if identity.Claims["nonce"] != query.Get("nonce") {
+		t.Errorf("ID token nonce = %v, want %s", identity.Claims["nonce"], query.Get("nonce"))
+	}
+
+	// The state is single use
+	if status, _ := get(t, client, callback.String()); status != http.StatusUnauthorized {
+		t.Errorf("replayed callback: got status %d, want 401", status)
+	}
+}
+
+func TestOAuthCallbackRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{} // ID token claims set by the provider
+		tamper func(t *testing.T, app *oauthApp, victim *http.Client, callback *url.URL) (*http.Client, *url.URL)
+	}{
+		{
+			name: "wrong state",
+			tamper: func(t *testing.T, app *oauthApp, victim *http.Client, callback *url.URL) (*http.Client, *url.URL) {
+				query := callback.Query()
+				query.Set("state", "forged")
+				callback.RawQuery = query.Encode()
+				return victim, callback
+			},
+		},
+		{
+			name: "no login started",
+			tamper: func(t *testing.T, app *oauthApp, victim *http.Client, callback *url.URL) (*http.Client, *url.URL) {
+				return app.client(), callback
+			},
+		},
+		{
+			// A code issued for another browser's PKCE challenge, injected
+			// with the victim's state, fails the code verifier check
+			name: "injected code",
+			tamper: func(t *testing.T, app *oauthApp, victim *http.Client, callback *url.URL) (*http.Client, *url.URL) {
+				attacker := app.authorize(t, app.client())
+				query := callback.Query()
+				query.Set("code", attacker.Query().Get("code"))
+				callback.RawQuery = query.Encode()
+				return victim, callback
+			},
+		},
+		{name: "wrong nonce", claims: map[string]interface{}{"nonce": "replayed"}},
+		{name: "wrong issuer", claims: map[string]interface{}{"iss": "https://evil.example.com"}},
+		{name: "wrong audience", claims: map[string]interface{}{"aud": "other-client"}},
+		{name: "expired", claims: map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newOAuthApp(t)
+			if tt.claims != nil {
+				app.idp.SetUser(rtesting.OIDCUser{Subject: "1", Email: "user@example.com", Claims: tt.claims})
+			}
+			client := app.client()
+			callback := app.authorize(t, client)
+			if tt.tamper != nil {
+				client, callback = tt.tamper(t, app, client, callback)
+			}
+
+			status, _ := get(t, client, callback.String())
+			if status == http.StatusSeeOther || status == http.StatusOK {
+				t.Fatalf("callback: got status %d, want the login refused", status)
+			}
+			if len(app.identities) != 0 {
+				t.Errorf("MapUser called with %+v", app.identities[0])
+			}
+		})
+	}
+}
+
+func TestOAuthIDTokenSignature(t *testing.T) {
+	app := newOAuthApp(t)
+	client := app.client()
+	get(t, client, app.authorize(t, client).String())
+	if len(app.identities) != 1 {
+		t.Fatalf("MapUser called %d times, want 1", len(app.identities))
+	}
+	token := app.identities[0].IDToken
+	nonce, _ := app.identities[0].Claims["nonce"].(string)
+	ctx := context.Background()
+
+	if _, err := app.provider.verifyIDToken(ctx, token, nonce); err != nil {
+		t.Fatalf("valid ID token rejected: %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	claims := map[string]interface{}{}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	claims["sub"] = "2"
+	payload, _ = json.Marshal(claims)
+	forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + parts[1] + "."
+	wrongKey := parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(make([]byte, 256))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"changed claims", forged},
+		{"alg none", unsigned},
+		{"bad signature", wrongKey},
+		{"malformed", "not.a.jwt"},
+	}
+	for _, tt := range tests {
+		if _, err := app.provider.verifyIDToken(ctx, tt.token, nonce); err == nil {
+			t.Errorf("%s: ID token accepted", tt.name)
+		}
+	}
+
+	// Tokens signed with a rotated key are verified after fetching the JWKS again
+	app.idp.RotateKey()
+	client = app.client()
+	get(t, client, app.authorize(t, client).String())
+	if len(app.identities) != 2 {
+		t.Errorf("login after key rotation failed")
+	}
+}
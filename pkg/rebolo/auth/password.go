@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hasher hashes and verifies passwords
+type Hasher interface {
+	Hash(password string) (string, error)
+	Check(hash, password string) bool
+}
+
+// BcryptHasher hashes passwords with bcrypt
+type BcryptHasher struct {
+	Cost int // Default: bcrypt.DefaultCost
+}
+
+// Hash returns the bcrypt hash of password
+func (h BcryptHasher) Hash(password string) (string, error) {
+	cost := h.Cost
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Check reports whether password matches a bcrypt hash
+func (h BcryptHasher) Check(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// Argon2Hasher hashes passwords with Argon2id in the PHC string format
+// ($argon2id$v=19$m=65536,t=1,p=4$salt$hash)
+type Argon2Hasher struct {
+	Time    uint32 // Default: 1
+	Memory  uint32 // KiB, default: 64 MiB
+	Threads uint8  // Default: 4
+	KeyLen  uint32 // Default: 32
+}
+
+func (h Argon2Hasher) withDefaults() Argon2Hasher {
+	if h.Time == 0 {
+		h.Time = 1
+	}
+	if h.Memory == 0 {
+		h.Memory = 64 * 1024
+	}
+	if h.Threads == 0 {
+		h.Threads = 4
+	}
+	if h.KeyLen == 0 {
+		h.KeyLen = 32
+	}
+	return h
+}
+
+// Hash returns the Argon2id hash of password
+func (h Argon2Hasher) Hash(password string) (string, error) {
+	h = h.withDefaults()
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, h.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Check reports whether password matches an Argon2id hash. The parameters
+// stored in the hash are used, so hashes keep working after tuning.
+func (h Argon2Hasher) Check(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false
+	}
+
+	key := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(expected)))
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+var (
+	hasher   Hasher = BcryptHasher{}
+	hasherMu sync.RWMutex
+)
+
+// SetHasher changes the algorithm used by HashPassword (default: bcrypt).
+// CheckPassword keeps verifying hashes of both algorithms.
+// Usage: auth.SetHasher(auth.Argon2Hasher{})
+func SetHasher(h Hasher) {
+	hasherMu.Lock()
+	defer hasherMu.Unlock()
+	hasher = h
+}
+
+// HashPassword hashes password with the configured hasher
+func HashPassword(password string) (string, error) {
+	hasherMu.RLock()
+	h := hasher
+	hasherMu.RUnlock()
+	return h.Hash(password)
+}
+
+// CheckPassword reports whether password matches hash, detecting the
+// algorithm from the hash
+func CheckPassword(hash, password string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		return Argon2Hasher{}.Check(hash, password)
+	}
+	if strings.HasPrefix(hash, "$2") {
+		return BcryptHasher{}.Check(hash, password)
+	}
+
+	hasherMu.RLock()
+	h := hasher
+	hasherMu.RUnlock()
+	return h.Check(hash, password)
+}
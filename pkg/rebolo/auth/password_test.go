@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashers(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher Hasher
+		prefix string
+	}{
+		{"bcrypt", BcryptHasher{Cost: bcrypt.MinCost}, "$2a$04$"},
+		{"argon2id", Argon2Hasher{Memory: 1024}, "$argon2id$v=19$m=1024,t=1,p=4$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := tt.hasher.Hash("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Errorf("hash %s doesn't start with %s", hash, tt.prefix)
+			}
+			if again, _ := tt.hasher.Hash("correct horse"); again == hash {
+				t.Error("hashing twice gave the same hash, the salt isn't random")
+			}
+
+			checks := []struct {
+				password string
+				want     bool
+			}{
+				{"correct horse", true},
+				{"Correct horse", false},
+				{"correct horse ", false},
+				{"", false},
+			}
+			for _, c := range checks {
+				if got := tt.hasher.Check(hash, c.password); got != c.want {
+					t.Errorf("Check(%q) = %v, want %v", c.password, got, c.want)
+				}
+				// CheckPassword detects the algorithm whatever the hasher
+				if got := CheckPassword(hash, c.password); got != c.want {
+					t.Errorf("CheckPassword(%q) = %v, want %v", c.password, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestArgon2CheckMalformed(t *testing.T) {
+	hash, err := Argon2Hasher{Memory: 1024}.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(hash, "$")
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"other algorithm", strings.Replace(hash, "argon2id", "argon2i", 1)},
+		{"other version", strings.Replace(hash, "v=19", "v=16", 1)},
+		{"bad parameters", strings.Replace(hash, parts[3], "m=x", 1)},
+		{"bad salt", strings.Replace(hash, parts[4], "!", 1)},
+		{"missing part", strings.Join(parts[:5], "$")},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		if (Argon2Hasher{}).Check(tt.hash, "secret") {
+			t.Errorf("%s: hash %q accepted", tt.name, tt.hash)
+		}
+	}
+}
+
+func TestSetHasher(t *testing.T) {
+	defer SetHasher(BcryptHasher{})
+
+	bcryptHash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetHasher(Argon2Hasher{Memory: 1024})
+	argon2Hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(bcryptHash, "$2") || !strings.HasPrefix(argon2Hash, "$argon2id$") {
+		t.Fatalf("got hashes %s and %s, want bcrypt then argon2id", bcryptHash, argon2Hash)
+	}
+	// Existing bcrypt hashes keep working after switching
+	for _, hash := range []string{bcryptHash, argon2Hash} {
+		if !CheckPassword(hash, "secret") {
+			t.Errorf("CheckPassword(%s) = false, want true", hash)
+		}
+	}
+}
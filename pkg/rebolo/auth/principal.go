@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Authentication methods of a Principal
+const (
+	MethodSession = "session"
+	MethodAPIKey  = "api_key"
+	MethodJWT     = "jwt"
+)
+
+// Principal is the authenticated caller of a request: a logged in user, an
+// API key or a bearer token
+type Principal struct {
+	Subject string   // ID of the user the credentials belong to
+	Method  string   // session, api_key or jwt
+	Scopes  []string // Granted scopes (API keys and tokens)
+	KeyID   string   // API key ID (api_key)
+	Claims  *Claims  // Token claims (jwt)
+}
+
+// HasScope reports whether the principal was granted scope. "*" grants
+// every scope. Session users aren't scoped and pass every check.
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+	if p.Method == MethodSession {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope || s == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+type principalContextKey struct{}
+
+// WithPrincipal returns a copy of r carrying p
+func WithPrincipal(r *http.Request, p *Principal) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalContextKey{}, p))
+}
+
+// PrincipalFrom returns the principal authenticated by APIKeyAuth or
+// BearerAuth, the user loaded by LoadUser, or nil
+func PrincipalFrom(r *http.Request) *Principal {
+	if r == nil {
+		return nil
+	}
+	if p, ok := r.Context().Value(principalContextKey{}).(*Principal); ok {
+		return p
+	}
+	if id := UserIDFrom(r); id != "" {
+		return &Principal{Subject: id, Method: MethodSession}
+	}
+	return nil
+}
+
+// RequireScope is a middleware that only lets principals with all of
+// scopes through. Anonymous requests get a 401 and principals missing a
+// scope get a 403.
+// Usage: app.GET("/api/posts", auth.RequireScope("posts:read")(handler).ServeHTTP)
+func RequireScope(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := PrincipalFrom(r)
+			if p == nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeJSONError(w, http.StatusUnauthorized, ErrUnauthenticated.Error())
+				return
+			}
+			for _, scope := range scopes {
+				if !p.HasScope(scope) {
+					w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+					writeJSONError(w, http.StatusForbidden, "missing scope: "+scope)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authenticated stores p in the request, and the user it belongs to when
+// findUser is set, so RequireLogin and ctx.CurrentUser() also work for API
+// clients
+func authenticated(r *http.Request, p *Principal, findUser UserFinder) (*http.Request, error) {
+	r = WithPrincipal(r, p)
+	if p.Subject == "" {
+		return r, nil
+	}
+
+	var user interface{}
+	if findUser != nil {
+		var err error
+		user, err = findUser(r.Context(), p.Subject)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return WithUser(r, p.Subject, user), nil
+}
+
+func writeJSONError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":  message,
+		"status": strconv.Itoa(code),
+	})
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+)
+
+// pruneInterval limits how often expired refresh tokens are deleted
+const pruneInterval = time.Minute
+
+// RefreshTokenStore keeps the IDs (jti) of issued refresh tokens so each
+// one is accepted once. JWT.Issue saves them and JWT.Refresh consumes them.
+type RefreshTokenStore interface {
+	// Save records an issued refresh token
+	Save(ctx context.Context, id, subject string, expiresAt time.Time) error
+	// Consume removes the token and reports whether it was still there,
+	// false for tokens already used, revoked or expired
+	Consume(ctx context.Context, id string) (bool, error)
+}
+
+// MemoryRefreshTokenStore keeps refresh tokens in memory. They are lost on
+// restart and not shared between instances, use SQLRefreshTokenStore then.
+type MemoryRefreshTokenStore struct {
+	mu         sync.Mutex
+	tokens     map[string]time.Time
+	lastPruned time.Time
+}
+
+// NewMemoryRefreshTokenStore creates an empty in-memory store
+func NewMemoryRefreshTokenStore() *MemoryRefreshTokenStore {
+	return &MemoryRefreshTokenStore{tokens: make(map[string]time.Time)}
+}
+
+// Save records an issued refresh token
+func (s *MemoryRefreshTokenStore) Save(ctx context.Context, id, subject string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastPruned) >= pruneInterval {
+		for tokenID, expires := range s.tokens {
+			if now.After(expires) {
+				delete(s.tokens, tokenID)
+			}
+		}
+		s.lastPruned = now
+	}
+	s.tokens[id] = expiresAt
+	return nil
+}
+
+// Consume removes the token and reports whether it was still valid
+func (s *MemoryRefreshTokenStore) Consume(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires, ok := s.tokens[id]
+	delete(s.tokens, id)
+	return ok && !time.Now().After(expires), nil
+}
+
+// SQLRefreshTokenStore keeps refresh tokens in a database table shared by
+// every instance:
+//
+//	CREATE TABLE refresh_tokens (
+//		id VARCHAR(64) PRIMARY KEY,
+//		subject VARCHAR(255) NOT NULL,
+//		expires_at BIGINT NOT NULL
+//	)
+type SQLRefreshTokenStore struct {
+	db    *sql.DB
+	table string
+
+	mu         sync.Mutex
+	lastPruned time.Time
+}
+
+// NewSQLRefreshTokenStore creates a store using the "refresh_tokens" table,
+// creating it if needed
+// Usage: store, err := auth.NewSQLRefreshTokenStore(app.DB())
+func NewSQLRefreshTokenStore(db *sql.DB) (*SQLRefreshTokenStore, error) {
+	return NewSQLRefreshTokenStoreWithTable(db, "refresh_tokens")
+}
+
+// NewSQLRefreshTokenStoreWithTable creates a refresh token store using table
+func NewSQLRefreshTokenStoreWithTable(db *sql.DB, table string) (*SQLRefreshTokenStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("refresh token store requires a database connection")
+	}
+	if !validTableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid refresh token table name: %s", table)
+	}
+
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(64) PRIMARY KEY,
+	subject VARCHAR(255) NOT NULL,
+	expires_at BIGINT NOT NULL
+)`, table)
+	if _, err := db.Exec(query); err != nil {
+		return nil, fmt.Errorf("failed to create refresh token table: %w", err)
+	}
+	return &SQLRefreshTokenStore{db: db, table: table}, nil
+}
+
+// Save records an issued refresh token
+func (s *SQLRefreshTokenStore) Save(ctx context.Context, id, subject string, expiresAt time.Time) error {
+	s.prune(ctx)
+
+	query := adapters.Rebind(s.db, fmt.Sprintf("INSERT INTO %s (id, subject, expires_at) VALUES (?, ?, ?)", s.table))
+	if _, err := s.db.ExecContext(ctx, query, id, subject, expiresAt.Unix()); err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return nil
+}
+
+// Consume deletes the token and reports whether it was still valid. The
+// delete is atomic, so of two concurrent uses only one succeeds.
+func (s *SQLRefreshTokenStore) Consume(ctx context.Context, id string) (bool, error) {
+	query := adapters.Rebind(s.db, fmt.Sprintf("DELETE FROM %s WHERE id = ? AND expires_at >= ?", s.table))
+	result, err := s.db.ExecContext(ctx, query, id, time.Now().Unix())
+	if err != nil {
+		return false, fmt.Errorf("failed to consume refresh token: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// prune deletes expired tokens, at most once per pruneInterval
+func (s *SQLRefreshTokenStore) prune(ctx context.Context) {
+	s.mu.Lock()
+	now := time.Now()
+	if now.Sub(s.lastPruned) < pruneInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPruned = now
+	s.mu.Unlock()
+
+	query := adapters.Rebind(s.db, fmt.Sprintf("DELETE FROM %s WHERE expires_at < ?", s.table))
+	s.db.ExecContext(ctx, query, now.Unix())
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRefreshTokenStores(t *testing.T) {
+	sqlStore, err := NewSQLRefreshTokenStore(openTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores := []struct {
+		name  string
+		store RefreshTokenStore
+	}{
+		{"memory", NewMemoryRefreshTokenStore()},
+		{"sql", sqlStore},
+	}
+	for _, s := range stores {
+		t.Run(s.name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now()
+			s.store.Save(ctx, "valid", "42", now.Add(time.Hour))
+			s.store.Save(ctx, "expired", "42", now.Add(-time.Hour))
+
+			tests := []struct {
+				id   string
+				want bool
+			}{
+				{"valid", true},
+				{"valid", false}, // Already used
+				{"expired", false},
+				{"unknown", false},
+			}
+			for _, tt := range tests {
+				got, err := s.store.Consume(ctx, tt.id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("Consume(%s) = %v, want %v", tt.id, got, tt.want)
+				}
+			}
+		})
+	}
+}
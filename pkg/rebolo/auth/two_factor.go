@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/policy"
+)
+
+// Session keys of the two-factor login step
+const (
+	twoFactorPendingKey   = "_2fa_pending"
+	twoFactorPendingAtKey = "_2fa_pending_at"
+	twoFactorVerifiedKey  = "_2fa_verified"
+)
+
+// TwoFactorTimeout is how long a user has to enter the code after the
+// password step
+const TwoFactorTimeout = 5 * time.Minute
+
+// TwoFactorUser is implemented by users that can have 2FA enabled
+type TwoFactorUser interface {
+	TwoFactorEnabled() bool
+}
+
+// TwoFactorConfig configures RequireTwoFactor
+type TwoFactorConfig struct {
+	Roles      []string // Roles that must use 2FA, e.g. admin (empty: every user)
+	EnrollPath string   // Where users without 2FA are sent (default: /2fa/setup)
+	VerifyPath string   // Where enrolled but unverified sessions are sent (default: /2fa)
+
+	// Enrolled reports whether user has 2FA set up (default: the user
+	// implements TwoFactorUser)
+	Enrolled func(user interface{}) bool
+}
+
+// BeginTwoFactor starts the second login step for userID after the
+// password was checked. The user isn't logged in until CompleteTwoFactor.
+// Usage:
+//
+//	if user.TwoFactorEnabled() {
+//		app.Auth().BeginTwoFactor(w, r, user.ID)
+//		http.Redirect(w, r, "/2fa", http.StatusSeeOther)
+//		return
+//	}
+func (a *Authenticator) BeginTwoFactor(w http.ResponseWriter, r *http.Request, userID interface{}) error {
+	sess, err := a.config.GetSession(r, w)
+	if err != nil {
+		return err
+	}
+
+	sess.Delete(a.config.SessionKey)
+	sess.Delete(twoFactorVerifiedKey)
+	sess.Set(twoFactorPendingKey, fmt.Sprint(userID))
+	sess.Set(twoFactorPendingAtKey, time.Now().Unix())
+	return sess.Save()
+}
+
+// PendingTwoFactorUser returns the ID of the user waiting for the second
+// login step, or "" if none or the step timed out
+func (a *Authenticator) PendingTwoFactorUser(r *http.Request) string {
+	sess, err := a.config.GetSession(r, nil)
+	if err != nil {
+		return ""
+	}
+
+	startedAt, _ := sess.Get(twoFactorPendingAtKey).(int64)
+	if time.Since(time.Unix(startedAt, 0)) > TwoFactorTimeout {
+		return ""
+	}
+	return sess.GetString(twoFactorPendingKey)
+}
+
+// CompleteTwoFactor logs in the pending user once their code was checked,
+// or marks the session of a logged in user as verified (e.g. right after
+// enrolling)
+func (a *Authenticator) CompleteTwoFactor(w http.ResponseWriter, r *http.Request) error {
+	userID := a.PendingTwoFactorUser(r)
+	if userID == "" {
+		userID = a.UserID(r)
+	}
+	if userID == "" {
+		return ErrUnauthenticated
+	}
+
+	sess, err := a.config.GetSession(r, w)
+	if err != nil {
+		return err
+	}
+	sess.Delete(twoFactorPendingKey)
+	sess.Delete(twoFactorPendingAtKey)
+	sess.Set(a.config.SessionKey, userID)
+	sess.Set(twoFactorVerifiedKey, true)
+	return sess.Regenerate()
+}
+
+// TwoFactorVerified reports whether the session passed the second login step
+func (a *Authenticator) TwoFactorVerified(r *http.Request) bool {
+	sess, err := a.config.GetSession(r, nil)
+	if err != nil {
+		return false
+	}
+	return sess.GetBool(twoFactorVerifiedKey)
+}
+
+// RequireTwoFactor is a middleware that sends users of the configured
+// roles to EnrollPath until they set up 2FA, and to VerifyPath when their
+// session hasn't passed the second step. It needs the user loaded by
+// LoadUser. JSON requests get a 403 instead.
+func (a *Authenticator) RequireTwoFactor(config TwoFactorConfig) func(http.Handler) http.Handler {
+	if config.EnrollPath == "" {
+		config.EnrollPath = "/2fa/setup"
+	}
+	if config.VerifyPath == "" {
+		config.VerifyPath = "/2fa"
+	}
+	if config.Enrolled == nil {
+		config.Enrolled = func(user interface{}) bool {
+			u, ok := user.(TwoFactorUser)
+			return ok && u.TwoFactorEnabled()
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := UserFrom(r)
+			if user == nil || (len(config.Roles) > 0 && !policy.HasRole(user, config.Roles...)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// API keys and tokens are issued separately from the login
+			if p := PrincipalFrom(r); p != nil && p.Method != MethodSession {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			target := ""
+			switch {
+			case !config.Enrolled(user):
+				target = config.EnrollPath
+			case !a.TwoFactorVerified(r):
+				target = config.VerifyPath
+			}
+			if target == "" || isUnderPath(r.URL.Path, config.EnrollPath) || isUnderPath(r.URL.Path, config.VerifyPath) ||
+				r.URL.Path == a.config.LoginPath {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if wantsJSON(r) {
+				writeJSONError(w, http.StatusForbidden, "two-factor authentication required")
+				return
+			}
+			http.Redirect(w, r, target, http.StatusSeeOther)
+		})
+	}
+}
+
+func isUnderPath(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
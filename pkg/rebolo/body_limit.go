@@ -0,0 +1,126 @@
+package rebolo
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/middleware"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/routing"
+	"github.com/gorilla/mux"
+)
+
+// bodyLimits holds the request body limits of the app: per route, per
+// group and server.max_body_size, checked in that order
+type bodyLimits struct {
+	router *adapters.MuxRouter
+	global int64
+
+	mu     sync.RWMutex
+	routes map[*mux.Route]int64
+	groups []groupBodyLimit // Nested groups come after their parents
+}
+
+type groupBodyLimit struct {
+	prefix *mux.Route
+	limit  int64
+}
+
+func newBodyLimits(router *adapters.MuxRouter, global int64) *bodyLimits {
+	return &bodyLimits{router: router, global: global, routes: make(map[*mux.Route]int64)}
+}
+
+// MaxBodySize sets the request body limit of a route, in place of
+// server.max_body_size (10MB by default), e.g. for uploads. Bodies over
+// the limit fail to bind with a 413 error. Routes with a larger limit
+// aren't cut off by server.read_timeout and write_timeout.
+// Usage: app.MaxBodySize(app.POST("/videos", upload), 500<<20)
+func (a *Application) MaxBodySize(route *routing.NamedRoute, limit int64) *routing.NamedRoute {
+	a.bodyLimits.mu.Lock()
+	a.bodyLimits.routes[route.Route] = limit
+	a.bodyLimits.mu.Unlock()
+	return route
+}
+
+// MaxBodySize sets the request body limit of the group routes, in place
+// of server.max_body_size (app.MaxBodySize still overrides it per route)
+// Usage: app.Group("/uploads").MaxBodySize(100 << 20)
+func (g *RouteGroup) MaxBodySize(limit int64) *RouteGroup {
+	limits := g.app.bodyLimits
+	limits.mu.Lock()
+	limits.groups = append(limits.groups, groupBodyLimit{prefix: g.prefix, limit: limit})
+	limits.mu.Unlock()
+	return g
+}
+
+// middleware limits request bodies before MethodOverride reads them.
+// Routes allowed more than server.max_body_size are uploads, which can
+// take longer than server.read_timeout and write_timeout, so their
+// connection deadlines are lifted.
+func (l *bodyLimits) middleware() middleware.MiddlewareFunc {
+	global := l.global
+	if global <= 0 {
+		global = middleware.DefaultMaxBodySize
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limit := l.limitFor(r)
+			if limit <= 0 {
+				limit = global
+			}
+			if limit > global {
+				rc := http.NewResponseController(w)
+				rc.SetReadDeadline(time.Time{})
+				rc.SetWriteDeadline(time.Time{})
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitFor returns the limit of the route r matches. POST forms may become
+// PUT, PATCH or DELETE with _method, which isn't known before reading the
+// body, so their routes are tried as well.
+func (l *bodyLimits) limitFor(r *http.Request) int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if len(l.routes) == 0 && len(l.groups) == 0 {
+		return l.global
+	}
+
+	methods := []string{r.Method}
+	if r.Method == http.MethodPost {
+		methods = append(methods, http.MethodPut, http.MethodPatch, http.MethodDelete)
+	}
+	for _, method := range methods {
+		probe := r
+		if method != r.Method {
+			probe = r.Clone(r.Context())
+			probe.Method = method
+		}
+
+		var match mux.RouteMatch
+		if !l.router.Match(probe, &match) || match.MatchErr != nil || match.Route == nil {
+			continue
+		}
+		if limit, ok := l.routes[match.Route]; ok {
+			return limit
+		}
+		for i := len(l.groups) - 1; i >= 0; i-- {
+			if l.groups[i].prefix.Match(probe, &mux.RouteMatch{}) {
+				return l.groups[i].limit
+			}
+		}
+		return l.global
+	}
+	return l.global
+}
@@ -0,0 +1,69 @@
+package rebolo
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/cache"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/ports"
+)
+
+// newCache creates the cache configured in the cache section of config.yml
+func newCache(configData ports.ConfigData, database adapters.DatabaseAdapter) *cache.Cache {
+	cfg := configData.Cache
+
+	switch strings.ToLower(cfg.Store) {
+	case "", "memory":
+		return cache.New(cache.NewMemoryStore(cfg.MaxEntries))
+	case "filesystem":
+		log.Printf("🗃️  Cache store: filesystem")
+		return cache.New(cache.NewFilesystemStore(cfg.Dir))
+	case "sql":
+		var db *sql.DB
+		if database != nil {
+			db, _ = database.DB().(*sql.DB)
+		}
+		table := cfg.Table
+		if table == "" {
+			table = "cache_entries"
+		}
+		store, err := cache.NewSQLStoreWithTable(db, table)
+		if err != nil {
+			log.Printf("⚠️  SQL cache store unavailable, caching in memory: %v", err)
+			return cache.New(cache.NewMemoryStore(cfg.MaxEntries))
+		}
+		log.Printf("🗃️  Cache store: sql (table %s)", table)
+		return cache.New(store)
+	default:
+		log.Printf("⚠️  Unknown cache store %q, caching in memory (supported: memory, filesystem, sql)", cfg.Store)
+		return cache.New(cache.NewMemoryStore(cfg.MaxEntries))
+	}
+}
+
+// Cache returns the app cache, for values that are expensive to compute
+// Usage:
+//
+//	var stats Stats
+//	err := app.Cache().Fetch("stats", time.Hour, &stats, func() (err error) {
+//		stats, err = loadStats()
+//		return err
+//	})
+func (a *Application) Cache() *cache.Cache {
+	return a.cache
+}
+
+// CachePage wraps a handler so its pages are cached for ttl, per path and
+// query, with tags for app.Cache().Invalidate (see cache.Middleware).
+// Logged in users always get a fresh page.
+// Usage: app.GET("/blog", app.CachePage(blogIndex, 10*time.Minute, "posts"))
+func (a *Application) CachePage(handler http.HandlerFunc, ttl time.Duration, tags ...string) http.HandlerFunc {
+	return cache.Middleware(cache.PageConfig{
+		Cache: a.cache,
+		TTL:   ttl,
+		Tags:  tags,
+	})(handler).ServeHTTP
+}
@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Cache keeps JSON-encoded values in a Store. Values may be tagged, so
+// Invalidate can drop every value of a tag at once (e.g. all pages
+// showing posts).
+type Cache struct {
+	store Store
+	tags  []string
+}
+
+// entry is what the store keeps per value: the value and the version of
+// each of its tags when it was written
+type entry struct {
+	Value json.RawMessage   `json:"v"`
+	Tags  map[string]string `json:"t,omitempty"`
+}
+
+// Store keys are prefixed, so values and tag versions can't clash
+const (
+	valuePrefix = "v:"
+	tagPrefix   = "t:"
+)
+
+// New creates a cache on top of store
+// Usage: c := cache.New(cache.NewMemoryStore(0))
+func New(store Store) *Cache {
+	return &Cache{store: store}
+}
+
+// Store returns the store of the cache
+func (c *Cache) Store() Store {
+	return c.store
+}
+
+// Tagged returns the cache tagging the values it writes with tags
+// Usage: app.Cache().Tagged("posts").Fetch("posts/popular", time.Hour, &posts, load)
+func (c *Cache) Tagged(tags ...string) *Cache {
+	return &Cache{store: c.store, tags: append(append([]string{}, c.tags...), tags...)}
+}
+
+// Get decodes the value of key into v (which may be nil to only check
+// that it's there) and reports whether it was found
+func (c *Cache) Get(key string, v interface{}) (bool, error) {
+	ctx := context.Background()
+	data, found, err := c.store.Get(ctx, valuePrefix+key)
+	if err != nil || !found {
+		return false, err
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return false, nil
+	}
+	if len(e.Tags) > 0 {
+		names := make([]string, 0, len(e.Tags))
+		for name := range e.Tags {
+			names = append(names, name)
+		}
+		versions, err := c.tagVersions(ctx, names, false)
+		if err != nil {
+			return false, err
+		}
+		for name, version := range e.Tags {
+			if versions[name] != version {
+				return false, nil
+			}
+		}
+	}
+
+	if v == nil {
+		return true, nil
+	}
+	if err := json.Unmarshal(e.Value, v); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// Set stores v under key for ttl (<= 0 for no expiry), with the tags of
+// the cache
+func (c *Cache) Set(key string, v interface{}, ttl time.Duration) error {
+	ctx := context.Background()
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	e := entry{Value: value}
+	if len(c.tags) > 0 {
+		if e.Tags, err = c.tagVersions(ctx, c.tags, true); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return c.store.Set(ctx, valuePrefix+key, data, ttl)
+}
+
+// Fetch decodes the value of key into v, or calls fn to compute it (fn
+// fills v) and stores the result for ttl. Store errors are logged, fn
+// still runs.
+// Usage:
+//
+//	var stats Stats
+//	err := app.Cache().Fetch("dashboard/stats", 10*time.Minute, &stats, func() (err error) {
+//		stats, err = loadStats(db)
+//		return err
+//	})
+func (c *Cache) Fetch(key string, ttl time.Duration, v interface{}, fn func() error) error {
+	found, err := c.Get(key, v)
+	if err != nil {
+		log.Printf("⚠️  Cache read failed for %s: %v", key, err)
+	}
+	if found {
+		return nil
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+	if err := c.Set(key, v, ttl); err != nil {
+		log.Printf("⚠️  Cache write failed for %s: %v", key, err)
+	}
+	return nil
+}
+
+// Delete removes the values of keys
+func (c *Cache) Delete(keys ...string) error {
+	ctx := context.Background()
+	for _, key := range keys {
+		if err := c.store.Delete(ctx, valuePrefix+key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Invalidate drops every value tagged with one of tags
+// Usage: app.Cache().Invalidate("posts")
+func (c *Cache) Invalidate(tags ...string) error {
+	ctx := context.Background()
+	for _, tag := range tags {
+		if err := c.store.Delete(ctx, tagPrefix+tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear removes every value of the store
+func (c *Cache) Clear() error {
+	return c.store.Clear(context.Background())
+}
+
+// tagVersions returns the current version of tags. Missing versions are
+// created when create is set, or left empty, matching no value.
+func (c *Cache) tagVersions(ctx context.Context, tags []string, create bool) (map[string]string, error) {
+	versions := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		version, found, err := c.store.Get(ctx, tagPrefix+tag)
+		if err != nil {
+			return nil, err
+		}
+		if !found && create {
+			version = newVersion()
+			if err := c.store.Set(ctx, tagPrefix+tag, version, 0); err != nil {
+				return nil, err
+			}
+		}
+		versions[tag] = string(version)
+	}
+	return versions, nil
+}
+
+func newVersion() []byte {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return []byte(hex.EncodeToString(b))
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/middleware"
+)
+
+// FragmentTag tags every fragment cached by the cache template helper, so
+// they can be dropped when templates change
+const FragmentTag = "fragments"
+
+var (
+	defaultCache   *Cache
+	defaultCacheMu sync.RWMutex
+)
+
+// SetDefault sets the cache used by the cache template helper.
+// rebolo.New() sets it to the application's cache.
+func SetDefault(c *Cache) {
+	defaultCacheMu.Lock()
+	defer defaultCacheMu.Unlock()
+	defaultCache = c
+}
+
+// Default returns the cache used by the cache template helper, creating a
+// memory cache when none was set
+func Default() *Cache {
+	defaultCacheMu.RLock()
+	c := defaultCache
+	defaultCacheMu.RUnlock()
+	if c != nil {
+		return c
+	}
+
+	defaultCacheMu.Lock()
+	defer defaultCacheMu.Unlock()
+	if defaultCache == nil {
+		defaultCache = New(NewMemoryStore(0))
+	}
+	return defaultCache
+}
+
+// Template helper caching the output of a template. Go templates can't
+// define blocks, so the fragment is a template of its own, rendered with
+// data on a miss. Requests with a CSRF token render it uncached, it may
+// embed the token. Tags follow data:
+//
+//	{{cache "sidebar" "10m" "shared/_sidebar.html" .}}
+//	{{cache (printf "post/%d" .Post.ID) "1h" "posts/_post.html" .Post "posts"}}
+func init() {
+	adapters.RegisterTemplateRenderHelper("cache", func(r *http.Request, templates *template.Template) interface{} {
+		return func(key string, ttl interface{}, name string, data interface{}, tags ...string) (template.HTML, error) {
+			return renderFragment(r, templates, key, ttl, name, data, tags)
+		}
+	})
+}
+
+func renderFragment(r *http.Request, templates *template.Template, key string, ttl interface{}, name string, data interface{}, tags []string) (template.HTML, error) {
+	if templates == nil {
+		return "", nil
+	}
+	// Fragments could embed the CSRF token of this session, render them
+	// uncached
+	if middleware.CSRFToken(r) != "" {
+		html, err := renderTemplate(templates, name, data)
+		return template.HTML(html), err
+	}
+	duration, err := parseTTL(ttl)
+	if err != nil {
+		return "", err
+	}
+
+	c := Default().Tagged(append([]string{FragmentTag}, tags...)...)
+	key = "fragment:" + key
+	nonce := middleware.CSPNonce(r)
+
+	var html string
+	if found, _ := c.Get(key, &html); found {
+		if nonce != "" {
+			html = string(bytes.ReplaceAll([]byte(html), []byte(noncePlaceholder), []byte(nonce)))
+		}
+		return template.HTML(html), nil
+	}
+
+	html, err = renderTemplate(templates, name, data)
+	if err != nil {
+		return "", err
+	}
+
+	stored := []byte(html)
+	if nonce != "" {
+		stored = bytes.ReplaceAll(stored, []byte(nonce), []byte(noncePlaceholder))
+	}
+	if err := c.Set(key, string(stored), duration); err != nil {
+		log.Printf("⚠️  Cache write failed for %s: %v", key, err)
+	}
+	return template.HTML(html), nil
+}
+
+// renderTemplate executes the fragment template name with data
+func renderTemplate(templates *template.Template, name string, data interface{}) (string, error) {
+	tmpl := templates.Lookup(name)
+	if tmpl == nil {
+		return "", fmt.Errorf("cache: template %s not found", name)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// parseTTL accepts durations ("10m"), time.Duration and seconds
+func parseTTL(ttl interface{}) (time.Duration, error) {
+	switch value := ttl.(type) {
+	case string:
+		return time.ParseDuration(value)
+	case time.Duration:
+		return value, nil
+	case int:
+		return time.Duration(value) * time.Second, nil
+	case int64:
+		return time.Duration(value) * time.Second, nil
+	default:
+		return 0, fmt.Errorf("cache: invalid ttl %v", ttl)
+	}
+}
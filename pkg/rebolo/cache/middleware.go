@@ -0,0 +1,236 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/auth"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/middleware"
+)
+
+// PageConfig configures Middleware
+type PageConfig struct {
+	Cache *Cache        // Required
+	TTL   time.Duration // Default: 1m
+
+	// VaryByUser caches a copy of the page per logged in user. Otherwise
+	// logged in users skip the cache, their pages may show their data.
+	VaryByUser bool
+
+	// IgnoreQuery shares the page between query strings, which are part of
+	// the key by default
+	IgnoreQuery bool
+
+	// Tags of the cached pages, for Cache.Invalidate
+	Tags []string
+
+	// MaxSize is the largest body cached (default: 1MB)
+	MaxSize int
+}
+
+// page is a cached response
+type page struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// noncePlaceholder replaces the CSP nonce in cached pages, each hit gets
+// its own
+const noncePlaceholder = "__rebolo_csp_nonce__"
+
+// Middleware caches whole GET responses, keyed by host, path, query and
+// user (see PageConfig). Only 200 responses that set no cookie and aren't
+// private are kept. Requests with a CSRF token skip the cache, their
+// pages may embed it. Every other anonymous visitor gets the same copy:
+// pages with flashes should cache fragments instead.
+// Usage: group.Use(cache.Middleware(cache.PageConfig{Cache: app.Cache(), TTL: 5 * time.Minute}))
+func Middleware(config PageConfig) func(http.Handler) http.Handler {
+	if config.TTL <= 0 {
+		config.TTL = time.Minute
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = 1 << 20
+	}
+	c := config.Cache
+	if len(config.Tags) > 0 {
+		c = c.Tagged(config.Tags...)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Pages of requests with a CSRF token embed it, they are per session
+			if r.Method != http.MethodGet && r.Method != http.MethodHead || middleware.CSRFToken(r) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			userID := auth.UserIDFrom(r)
+			if userID != "" && !config.VaryByUser {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := pageKey(r, userID, config.IgnoreQuery)
+			var cached page
+			if found, _ := c.Get(key, &cached); found {
+				writePage(w, r, &cached)
+				return
+			}
+
+			pw := &pageWriter{ResponseWriter: w, before: w.Header().Clone(), status: http.StatusOK, maxSize: config.MaxSize}
+			next.ServeHTTP(pw, r)
+
+			if p := pw.page(r); p != nil {
+				if err := c.Set(key, p, config.TTL); err != nil {
+					log.Printf("⚠️  Cache write failed for %s: %v", key, err)
+				}
+			}
+		})
+	}
+}
+
+// pageKey identifies the page of r, with the query sorted
+func pageKey(r *http.Request, userID string, ignoreQuery bool) string {
+	key := "page:" + r.Host + r.URL.Path
+	if !ignoreQuery {
+		if query := r.URL.Query().Encode(); query != "" {
+			key += "?" + query
+		}
+	}
+	if userID != "" {
+		key += "#user:" + userID
+	}
+	return key
+}
+
+// writePage answers r with a cached page
+func writePage(w http.ResponseWriter, r *http.Request, p *page) {
+	header := w.Header()
+	for name, values := range p.Header {
+		header[name] = values
+	}
+	header.Set("X-Cache", "HIT")
+
+	body := p.Body
+	if nonce := middleware.CSPNonce(r); nonce != "" {
+		body = bytes.ReplaceAll(body, []byte(noncePlaceholder), []byte(nonce))
+	}
+	w.WriteHeader(p.Status)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// pageWriter sends the response and keeps a copy of it
+type pageWriter struct {
+	http.ResponseWriter
+	before  http.Header // Headers set before the handler ran, not cached
+	maxSize int
+
+	status      int
+	header      http.Header // Headers set by the handler
+	body        bytes.Buffer
+	wroteHeader bool
+	uncacheable bool
+}
+
+func (pw *pageWriter) WriteHeader(code int) {
+	if pw.wroteHeader {
+		pw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	// Informational responses go out right away
+	if code >= 100 && code < 200 {
+		pw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	pw.wroteHeader = true
+	pw.status = code
+	pw.header = pw.handlerHeader()
+	pw.Header().Set("X-Cache", "MISS")
+	pw.ResponseWriter.WriteHeader(code)
+}
+
+func (pw *pageWriter) Write(b []byte) (int, error) {
+	if !pw.wroteHeader {
+		pw.WriteHeader(http.StatusOK)
+	}
+	if !pw.uncacheable {
+		if pw.body.Len()+len(b) > pw.maxSize {
+			pw.uncacheable = true
+			pw.body.Reset()
+		} else {
+			pw.body.Write(b)
+		}
+	}
+	return pw.ResponseWriter.Write(b)
+}
+
+// handlerHeader returns the headers the handler set or changed
+func (pw *pageWriter) handlerHeader() http.Header {
+	header := make(http.Header)
+	for name, values := range pw.Header() {
+		if before, ok := pw.before[name]; ok && strings.Join(before, "\n") == strings.Join(values, "\n") {
+			continue
+		}
+		header[name] = values
+	}
+	return header
+}
+
+// page returns the response to cache, or nil when it must not be
+func (pw *pageWriter) page(r *http.Request) *page {
+	if !pw.wroteHeader || pw.uncacheable || pw.status != http.StatusOK || r.Method == http.MethodHead {
+		return nil
+	}
+	// Cookies may also come from middleware around the cache (CSRF, flashes),
+	// so check the response as sent
+	if pw.ResponseWriter.Header().Get("Set-Cookie") != "" {
+		return nil
+	}
+	header := pw.header
+	if header.Get("Content-Encoding") != "" {
+		return nil
+	}
+	cacheControl := strings.ToLower(header.Get("Cache-Control"))
+	if strings.Contains(cacheControl, "private") || strings.Contains(cacheControl, "no-store") {
+		return nil
+	}
+
+	body := pw.body.Bytes()
+	if nonce := middleware.CSPNonce(r); nonce != "" {
+		body = bytes.ReplaceAll(body, []byte(nonce), []byte(noncePlaceholder))
+	}
+	return &page{Status: pw.status, Header: header, Body: body}
+}
+
+// Flush supports streaming responses, which are cached once complete
+func (pw *pageWriter) Flush() {
+	if !pw.wroteHeader {
+		pw.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := pw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack supports websockets through the middleware
+func (pw *pageWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := pw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("cache: response can't be hijacked")
+	}
+	pw.uncacheable = true
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (pw *pageWriter) Unwrap() http.ResponseWriter {
+	return pw.ResponseWriter
+}
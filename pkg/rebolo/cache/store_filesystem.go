@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FilesystemStore keeps each value in its own file, surviving restarts
+type FilesystemStore struct {
+	dir         string
+	mu          sync.RWMutex
+	lastCleanup time.Time
+}
+
+// NewFilesystemStore creates a store writing to files in dir (default:
+// tmp/cache)
+func NewFilesystemStore(dir string) *FilesystemStore {
+	if dir == "" {
+		dir = filepath.Join("tmp", "cache")
+	}
+	return &FilesystemStore{dir: dir, lastCleanup: time.Now()}
+}
+
+// path hashes key, which may hold any character
+func (s *FilesystemStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, "cache_"+hex.EncodeToString(sum[:]))
+}
+
+// Files hold the expiry as an 8 byte Unix timestamp (0 for none) followed
+// by the value
+func (s *FilesystemStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.cleanup()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.load(s.path(key))
+}
+
+func (s *FilesystemStore) load(path string) ([]byte, bool, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(content) < 8 {
+		return nil, false, nil
+	}
+
+	if expires := int64(binary.BigEndian.Uint64(content[:8])); expires != 0 && time.Now().Unix() >= expires {
+		return nil, false, nil
+	}
+	return content[8:], true, nil
+}
+
+func (s *FilesystemStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	var expires int64
+	if ttl > 0 {
+		expires = time.Now().Add(ttl).Unix() + 1
+	}
+	content := make([]byte, 8, 8+len(value))
+	binary.BigEndian.PutUint64(content, uint64(expires))
+	content = append(content, value...)
+
+	// Write to a temporary file first so readers never see partial data
+	path := s.path(key)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *FilesystemStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path(key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *FilesystemStore) Clear(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.removeFiles(func(string) bool { return true })
+}
+
+// cleanup removes expired files about once a minute
+func (s *FilesystemStore) cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.lastCleanup) < cleanupInterval {
+		return
+	}
+	s.lastCleanup = time.Now()
+	s.removeFiles(func(path string) bool {
+		_, found, err := s.load(path)
+		return err == nil && !found
+	})
+}
+
+// removeFiles deletes the cache files for which remove returns true (with
+// s.mu held)
+func (s *FilesystemStore) removeFiles(remove func(path string) bool) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "cache_") {
+			continue
+		}
+		if path := filepath.Join(s.dir, name); remove(path) {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+)
+
+// SQLStore keeps values in a database table so every instance of the app
+// shares them:
+//
+//	CREATE TABLE cache_entries (
+//		id VARCHAR(64) PRIMARY KEY,
+//		data TEXT NOT NULL,
+//		expires_at BIGINT NOT NULL
+//	)
+//
+// Rows are keyed by the SHA-256 of the key, expires_at is 0 for no expiry.
+type SQLStore struct {
+	db          *sql.DB
+	table       string
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+var validTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewSQLStore creates a store using the "cache_entries" table, creating it
+// if needed
+// Usage: store, err := cache.NewSQLStore(app.DB())
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	return NewSQLStoreWithTable(db, "cache_entries")
+}
+
+// NewSQLStoreWithTable creates a SQL store using table
+func NewSQLStoreWithTable(db *sql.DB, table string) (*SQLStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("sql cache store requires a database connection")
+	}
+	if !validTableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid cache table name: %s", table)
+	}
+
+	s := &SQLStore{db: db, table: table, lastCleanup: time.Now()}
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(64) PRIMARY KEY,
+	data TEXT NOT NULL,
+	expires_at BIGINT NOT NULL
+)`, table)
+	if _, err := db.Exec(query); err != nil {
+		return nil, fmt.Errorf("failed to create cache table: %w", err)
+	}
+	return s, nil
+}
+
+func sqlID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *SQLStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.cleanup()
+
+	query := adapters.Rebind(s.db, fmt.Sprintf("SELECT data FROM %s WHERE id = ? AND (expires_at = 0 OR expires_at > ?)", s.table))
+
+	var encoded string
+	err := s.db.QueryRowContext(ctx, query, sqlID(key), time.Now().Unix()).Scan(&encoded)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set replaces the row inside a transaction, which works the same on
+// PostgreSQL, MySQL and SQLite
+func (s *SQLStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var expires int64
+	if ttl > 0 {
+		expires = time.Now().Add(ttl).Unix() + 1
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id := sqlID(key)
+	if _, err := tx.ExecContext(ctx, adapters.Rebind(s.db, fmt.Sprintf("DELETE FROM %s WHERE id = ?", s.table)), id); err != nil {
+		return err
+	}
+
+	insert := adapters.Rebind(s.db, fmt.Sprintf("INSERT INTO %s (id, data, expires_at) VALUES (?, ?, ?)", s.table))
+	if _, err := tx.ExecContext(ctx, insert, id, base64.StdEncoding.EncodeToString(value), expires); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) Delete(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, adapters.Rebind(s.db, fmt.Sprintf("DELETE FROM %s WHERE id = ?", s.table)), sqlID(key))
+	return err
+}
+
+func (s *SQLStore) Clear(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", s.table))
+	return err
+}
+
+// cleanup removes expired rows about once a minute
+func (s *SQLStore) cleanup() {
+	s.mu.Lock()
+	if time.Since(s.lastCleanup) < cleanupInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastCleanup = time.Now()
+	s.mu.Unlock()
+
+	go s.db.Exec(adapters.Rebind(s.db, fmt.Sprintf("DELETE FROM %s WHERE expires_at <> 0 AND expires_at <= ?", s.table)), time.Now().Unix())
+}
@@ -2,10 +2,16 @@ package context
 
 import (
 	"encoding/json"
+	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/auth"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/httpcache"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/logging"
 	"github.com/Palaciodiego008/rebololang/pkg/rebolo/session"
 	"github.com/Palaciodiego008/rebololang/pkg/rebolo/validation"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/worker"
 	"github.com/gorilla/mux"
 )
 
@@ -14,6 +20,8 @@ type AppContext interface {
 	GetSession(r *http.Request, w http.ResponseWriter) (*session.Session, error)
 	Bind(r *http.Request, v interface{}) error
 	RenderHTML(w http.ResponseWriter, template string, data interface{}) error
+	RenderHTMLWithRequest(w http.ResponseWriter, r *http.Request, template string, data interface{}) error
+	Perform(job worker.Job) error
 }
 
 // Context wraps http.Request and http.ResponseWriter with convenient helpers
@@ -48,6 +56,42 @@ func (c *Context) Flash() (*session.Flash, error) {
 	return session.NewFlash(sess), nil
 }
 
+// CurrentUser returns the logged in user loaded by the auth middleware
+// (see app.EnableAuth), or nil
+// Usage: user, _ := ctx.CurrentUser().(*models.User)
+func (c *Context) CurrentUser() interface{} {
+	return auth.UserFrom(c.Request)
+}
+
+// CurrentUserID returns the ID of the logged in user, or ""
+func (c *Context) CurrentUserID() string {
+	return auth.UserIDFrom(c.Request)
+}
+
+// Principal returns the authenticated caller: a logged in user, an API key
+// or a bearer token, or nil
+// Usage: if p := ctx.Principal(); p != nil && p.HasScope("posts:write") { ... }
+func (c *Context) Principal() *auth.Principal {
+	return auth.PrincipalFrom(c.Request)
+}
+
+// RequestID returns the ID of the request (see middleware.RequestID)
+func (c *Context) RequestID() string {
+	return logging.RequestID(c.Request.Context())
+}
+
+// Logger returns the app logger with the request ID
+// Usage: ctx.Logger().Info("post created", "id", post.ID)
+func (c *Context) Logger() *slog.Logger {
+	return logging.FromContext(c.Request.Context())
+}
+
+// Perform enqueues a background job carrying the request ID (see
+// worker.WithRequestID)
+func (c *Context) Perform(job worker.Job) error {
+	return c.App.Perform(worker.WithRequestID(c.Request.Context(), job))
+}
+
 // Param retrieves a URL parameter by name (from gorilla/mux)
 func (c *Context) Param(key string) string {
 	return c.params[key]
@@ -70,7 +114,7 @@ func (c *Context) Bind(v interface{}) error {
 
 // Render renders an HTML template with data
 func (c *Context) Render(template string, data interface{}) error {
-	return c.App.RenderHTML(c.Response, template, data)
+	return c.App.RenderHTMLWithRequest(c.Response, c.Request, template, data)
 }
 
 // JSON sends a JSON response
@@ -110,6 +154,32 @@ func (c *Context) Get(key string) string {
 	return c.Request.Header.Get(key)
 }
 
+// Fresh sets the ETag and Last-Modified of the response and answers 304
+// Not Modified when the client's copy matches them (see httpcache.Fresh)
+// Usage: if ctx.Fresh(post.Version, post.UpdatedAt) { return nil }
+func (c *Context) Fresh(etag string, lastModified time.Time) bool {
+	return httpcache.Fresh(c.Response, c.Request, etag, lastModified)
+}
+
+// CachePublic lets browsers and CDNs cache the response for maxAge
+func (c *Context) CachePublic(maxAge time.Duration) *Context {
+	httpcache.Public(c.Response, maxAge)
+	return c
+}
+
+// CachePrivate lets only the browser cache the response for maxAge (0 to
+// revalidate it every time)
+func (c *Context) CachePrivate(maxAge time.Duration) *Context {
+	httpcache.Private(c.Response, maxAge)
+	return c
+}
+
+// NoStore keeps the response out of every cache
+func (c *Context) NoStore() *Context {
+	httpcache.NoStore(c.Response)
+	return c
+}
+
 // Method returns the HTTP method
 func (c *Context) Method() string {
 	return c.Request.Method
@@ -3,6 +3,8 @@ package core
 import (
 	"context"
 	"net/http"
+	"sync"
+	"time"
 )
 
 // App represents the core application
@@ -12,6 +14,8 @@ type App struct {
 	database   Database
 	renderer   Renderer
 	middleware []Middleware
+	server     *http.Server
+	serverMu   sync.Mutex
 }
 
 // Config interface for configuration
@@ -23,6 +27,17 @@ type Config interface {
 	GetDatabaseDebug() bool
 	GetEnvironment() string
 	IsHotReload() bool
+	GetServerConfig() ServerConfig
+}
+
+// ServerConfig holds the http.Server limits. Zero values use the defaults
+// of NewServer.
+type ServerConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
 }
 
 // NamedRoute is a type alias for route naming support
@@ -89,19 +104,74 @@ func (a *App) Start() error {
 		}
 	}
 
+	port := a.config.GetPort()
+	if port == "" {
+		port = "3000"
+	}
+
+	server := a.NewServer(":" + port)
+	a.serverMu.Lock()
+	a.server = server
+	a.serverMu.Unlock()
+
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// NewServer returns an http.Server for the app with the timeouts of the
+// config, so slow clients can't hold connections open. Bodies have no read
+// timeout by default: uploads are bounded by their size limit instead.
+func (a *App) NewServer(addr string) *http.Server {
+	cfg := a.config.GetServerConfig()
+	if cfg.ReadHeaderTimeout <= 0 {
+		cfg.ReadHeaderTimeout = 10 * time.Second
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 60 * time.Second
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 120 * time.Second
+	}
+	if cfg.MaxHeaderBytes <= 0 {
+		cfg.MaxHeaderBytes = http.DefaultMaxHeaderBytes
+	}
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           a.Handler(),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// StopServer stops the server started by Start, waiting for the requests
+// in progress until ctx is done
+func (a *App) StopServer(ctx context.Context) error {
+	a.serverMu.Lock()
+	server := a.server
+	a.serverMu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
+
+// Handler returns the router wrapped with the application middleware, as
+// served by Start (e.g. for httptest or a custom http.Server)
+func (a *App) Handler() http.Handler {
 	// Apply middleware - wrap the router with middleware in reverse order
 	// (first middleware becomes outermost, last becomes innermost)
 	var handler http.Handler = a.router
 	for i := len(a.middleware) - 1; i >= 0; i-- {
 		handler = a.middleware[i](handler)
 	}
-
-	port := a.config.GetPort()
-	if port == "" {
-		port = "3000"
-	}
-
-	return http.ListenAndServe(":"+port, handler)
+	return handler
 }
 
 // AddMiddleware adds middleware to the application
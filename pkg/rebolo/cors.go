@@ -0,0 +1,137 @@
+package rebolo
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/middleware"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/routing"
+	"github.com/gorilla/mux"
+)
+
+// corsPolicies holds the CORS policies of the app: per route, per group
+// and the EnableCORS one, checked in that order
+type corsPolicies struct {
+	mu         sync.RWMutex
+	global     *middleware.CORSPolicy
+	routes     map[*mux.Route]*middleware.CORSPolicy
+	groups     []groupCORSPolicy // Nested groups come after their parents
+	middleware *middleware.MiddlewareConfig
+}
+
+type groupCORSPolicy struct {
+	prefix *mux.Route
+	policy *middleware.CORSPolicy
+}
+
+// EnableCORS allows cross-origin requests to every route, as configured in
+// the cors section of config.yml unless a config is given. Preflights are
+// answered by OPTIONS routes added to the router, only for paths and
+// methods that have a route: other paths still get a 404.
+// Usage: app.EnableCORS(middleware.CORSConfig{AllowOrigins: []string{"https://app.example.com"}})
+func (a *Application) EnableCORS(configs ...middleware.CORSConfig) *middleware.MiddlewareConfig {
+	var config middleware.CORSConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	} else {
+		cfg := a.config.data.CORS
+		config = middleware.CORSConfig{
+			AllowOrigins:     cfg.AllowOrigins,
+			AllowMethods:     cfg.AllowMethods,
+			AllowHeaders:     cfg.AllowHeaders,
+			ExposeHeaders:    cfg.ExposeHeaders,
+			AllowCredentials: cfg.AllowCredentials,
+			MaxAge:           cfg.MaxAge,
+		}
+	}
+
+	cors := a.corsPolicies()
+	cors.mu.Lock()
+	cors.global = middleware.NewCORSPolicy(config)
+	cors.mu.Unlock()
+	return cors.middleware
+}
+
+// CORS sets the CORS policy of a route, in place of the EnableCORS one
+// Usage: app.CORS(app.GET("/api/feed", feed), middleware.CORSConfig{AllowOrigins: []string{"*"}})
+func (a *Application) CORS(route *routing.NamedRoute, config middleware.CORSConfig) *routing.NamedRoute {
+	cors := a.corsPolicies()
+	cors.mu.Lock()
+	cors.routes[route.Route] = middleware.NewCORSPolicy(config)
+	cors.mu.Unlock()
+	return route
+}
+
+// CORS sets the CORS policy of the group routes, in place of the
+// EnableCORS one (app.CORS still overrides it per route)
+// Usage: app.Group("/api").CORS(middleware.CORSConfig{AllowOrigins: []string{"*"}})
+func (g *RouteGroup) CORS(config middleware.CORSConfig) *RouteGroup {
+	cors := g.app.corsPolicies()
+	cors.mu.Lock()
+	cors.groups = append(cors.groups, groupCORSPolicy{prefix: g.prefix, policy: middleware.NewCORSPolicy(config)})
+	cors.mu.Unlock()
+	return g
+}
+
+// corsPolicies returns the app policies, adding the CORS middleware and the
+// preflight route the first time (see installCORS)
+func (a *Application) corsPolicies() *corsPolicies {
+	a.corsOnce.Do(a.installCORS)
+	return a.cors
+}
+
+// installCORS adds the CORS middleware and the preflight route
+func (a *Application) installCORS() {
+	a.cors = &corsPolicies{routes: make(map[*mux.Route]*middleware.CORSPolicy)}
+
+	// Preflights skip the method check of the route they are for
+	a.router.Methods(http.MethodOptions).
+		MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+			return middleware.IsPreflight(r) && a.corsPolicyFor(r, r.Header.Get("Access-Control-Request-Method")) != nil
+		}).
+		HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			a.corsPolicyFor(r, r.Header.Get("Access-Control-Request-Method")).Preflight(w, r)
+		})
+
+	a.cors.middleware = a.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Preflights are routed to the handler above
+			if !middleware.IsPreflight(r) {
+				if policy := a.corsPolicyFor(r, r.Method); policy != nil {
+					policy.SetHeaders(w, r)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+}
+
+// corsPolicyFor returns the policy of the route r would match with method,
+// or nil when no route matches or CORS isn't enabled for it
+func (a *Application) corsPolicyFor(r *http.Request, method string) *middleware.CORSPolicy {
+	probe := r
+	if method != r.Method {
+		probe = r.Clone(r.Context())
+		probe.Method = method
+		probe.Header.Del("Access-Control-Request-Method")
+	}
+
+	var match mux.RouteMatch
+	if !a.router.Match(probe, &match) || match.MatchErr != nil || match.Route == nil {
+		return nil
+	}
+
+	cors := a.cors
+	cors.mu.RLock()
+	defer cors.mu.RUnlock()
+
+	if policy, ok := cors.routes[match.Route]; ok {
+		return policy
+	}
+	for i := len(cors.groups) - 1; i >= 0; i-- {
+		if cors.groups[i].prefix.Match(probe, &mux.RouteMatch{}) {
+			return cors.groups[i].policy
+		}
+	}
+	return cors.global
+}
@@ -0,0 +1,48 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// HTTPError is an error carrying the HTTP status it should be reported with.
+// Context handlers can return it to get e.g. a 403 page instead of a 500.
+type HTTPError struct {
+	Code int
+	Err  error
+}
+
+// NewHTTPError creates an HTTPError
+// Usage: return errors.NewHTTPError(http.StatusForbidden, err)
+func NewHTTPError(code int, err error) *HTTPError {
+	return &HTTPError{Code: code, Err: err}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err == nil {
+		return http.StatusText(e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns the status of the HTTPError in err's chain, 413 for
+// bodies over the size limit, 504 for expired request deadlines, or 500
+func StatusCode(err error) int {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.Code != 0 {
+		return httpErr.Code
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,102 @@
+package rebolo
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/core"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/middleware"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/resource"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/routing"
+	"github.com/gorilla/mux"
+)
+
+// RouteGroup registers routes under a path prefix sharing middleware. The
+// group middleware runs after the global one, only for matched routes of
+// the group.
+type RouteGroup struct {
+	app    *Application
+	prefix *mux.Route // The PathPrefix route holding the group routes
+	router *adapters.MuxRouter
+	stack  *middleware.MiddlewareStack
+	parent *RouteGroup // Enclosing group, its middleware runs first
+}
+
+// Group creates a route group for paths starting with prefix
+// Usage:
+//
+//	admin := app.Group("/admin", app.Auth().RequireLogin)
+//	admin.GET("/", dashboard)            // /admin/
+//	admin.Resource("/posts", &PostsController{})
+func (a *Application) Group(prefix string, middlewares ...middleware.MiddlewareFunc) *RouteGroup {
+	return newRouteGroup(a, nil, a.router.PathPrefix(prefix), middlewares)
+}
+
+func newRouteGroup(app *Application, parent *RouteGroup, prefix *mux.Route, middlewares []middleware.MiddlewareFunc) *RouteGroup {
+	g := &RouteGroup{
+		app:    app,
+		prefix: prefix,
+		stack:  middleware.NewMiddlewareStack(),
+		parent: parent,
+	}
+	g.router = &adapters.MuxRouter{Router: prefix.Subrouter(), Wrap: g.wrap}
+	for _, mw := range middlewares {
+		g.stack.Use(mw)
+	}
+	return g
+}
+
+// wrap runs the group middleware around a route handler. The chain is built
+// on the first request, so middleware added after the route still applies.
+func (g *RouteGroup) wrap(handler http.Handler) http.Handler {
+	var once sync.Once
+	var chain http.Handler
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			chain = handler
+			for group := g; group != nil; group = group.parent {
+				chain = group.stack.Apply(chain)
+			}
+		})
+		chain.ServeHTTP(w, r)
+	})
+}
+
+// Use adds a middleware to the group
+// Returns the MiddlewareConfig to allow chaining with Skip()
+func (g *RouteGroup) Use(mw middleware.MiddlewareFunc) *middleware.MiddlewareConfig {
+	return g.stack.Use(mw)
+}
+
+// Group creates a nested group, running this group's middleware first
+func (g *RouteGroup) Group(prefix string, middlewares ...middleware.MiddlewareFunc) *RouteGroup {
+	return newRouteGroup(g.app, g, g.router.PathPrefix(prefix), middlewares)
+}
+
+func (g *RouteGroup) GET(path string, handler http.HandlerFunc) *routing.NamedRoute {
+	return g.router.GET(path, handler).(*routing.NamedRoute)
+}
+
+func (g *RouteGroup) POST(path string, handler http.HandlerFunc) *routing.NamedRoute {
+	return g.router.POST(path, handler).(*routing.NamedRoute)
+}
+
+func (g *RouteGroup) PUT(path string, handler http.HandlerFunc) *routing.NamedRoute {
+	return g.router.PUT(path, handler).(*routing.NamedRoute)
+}
+
+func (g *RouteGroup) DELETE(path string, handler http.HandlerFunc) *routing.NamedRoute {
+	return g.router.DELETE(path, handler).(*routing.NamedRoute)
+}
+
+// Resource registers a RESTful resource in the group (see app.Resource)
+func (g *RouteGroup) Resource(path string, controller core.Controller) {
+	g.app.resource(g.router, path, controller)
+}
+
+// ResourceWithContext registers a Context-based resource in the group (see
+// app.ResourceWithContext)
+func (g *RouteGroup) ResourceWithContext(path string, res resource.Resource) {
+	g.app.resourceWithContext(g.router, path, res)
+}
@@ -0,0 +1,127 @@
+package httpcache
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WeakETag returns a weak ETag for body, e.g. W/"1f3a9c0b5e7d2a41"
+func WeakETag(body []byte) string {
+	h := fnv.New64a()
+	h.Write(body)
+	return fmt.Sprintf(`W/"%016x"`, h.Sum64())
+}
+
+// quoteETag quotes etag as a strong ETag unless it is quoted already
+func quoteETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// Fresh sets the ETag and Last-Modified headers of the response and
+// reports whether the client's cached copy is still valid, in which case
+// it answers 304 Not Modified: the handler must not write a body. etag is
+// quoted when needed, zero values are ignored. Only GET and HEAD requests
+// can be fresh.
+// Usage: if httpcache.Fresh(w, r, post.Version, post.UpdatedAt) { return }
+func Fresh(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	etag = quoteETag(etag)
+	header := w.Header()
+	if etag != "" {
+		header.Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if !IsFresh(r, etag, lastModified) {
+		return false
+	}
+	NotModified(w)
+	return true
+}
+
+// IsFresh reports whether the conditional headers of r match etag or
+// lastModified, without writing anything. If-None-Match takes precedence
+// over If-Modified-Since, as in RFC 9110.
+func IsFresh(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etag != "" && matchETag(inm, quoteETag(etag))
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	// HTTP dates have a precision of one second
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// matchETag reports whether the If-None-Match list matches etag, with the
+// weak comparison
+func matchETag(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// NotModified answers 304 Not Modified, dropping the headers that describe
+// a body. The CSP is dropped too: browsers would apply a new nonce to
+// their copy, rendered with the old one.
+func NotModified(w http.ResponseWriter) {
+	header := w.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+	header.Del("Content-Security-Policy")
+	header.Del("Content-Security-Policy-Report-Only")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// Public lets browsers and shared caches (CDNs, proxies) keep the
+// response for maxAge
+// Usage: httpcache.Public(w, time.Hour)
+func Public(w http.ResponseWriter, maxAge time.Duration) {
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", seconds(maxAge)))
+}
+
+// Private lets only the browser keep the response for maxAge, for pages of
+// logged in users. A zero maxAge makes it revalidate on every use, with
+// the ETag or Last-Modified of the response.
+func Private(w http.ResponseWriter, maxAge time.Duration) {
+	if maxAge <= 0 {
+		w.Header().Set("Cache-Control", "private, no-cache")
+		return
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", seconds(maxAge)))
+}
+
+// NoStore keeps the response out of every cache, for sensitive data
+func NoStore(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+func seconds(d time.Duration) int64 {
+	if d < 0 {
+		return 0
+	}
+	return int64(d / time.Second)
+}
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var defaultLogger atomic.Pointer[slog.Logger]
+
+// NewLogger creates a slog logger writing text (default) or JSON lines to
+// w (default: the standard log output)
+func NewLogger(w io.Writer, format string, level slog.Level) *slog.Logger {
+	if w == nil {
+		w = log.Writer()
+	}
+	options := &slog.HandlerOptions{Level: level}
+	if format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
+}
+
+// ParseLevel parses debug, info (default), warn or error
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// SetLogger sets the logger returned by Default (the app sets it from the
+// logging section of config.yml)
+func SetLogger(logger *slog.Logger) {
+	defaultLogger.Store(logger)
+}
+
+// Default returns the app logger, or slog.Default() when none was set
+func Default() *slog.Logger {
+	if logger := defaultLogger.Load(); logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
+
+type requestIDContextKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or ""
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// FromContext returns the app logger with the request ID of ctx
+// Usage: logging.FromContext(r.Context()).Info("post created", "id", post.ID)
+func FromContext(ctx context.Context) *slog.Logger {
+	logger := Default()
+	if id := RequestID(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	return logger
+}
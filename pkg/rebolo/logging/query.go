@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"context"
 	"log"
 	"time"
 )
@@ -38,3 +39,30 @@ func LogQueryError(query string, err error, args ...interface{}) {
 		log.Printf("%s[SQL Args]%s %v", ColorCyan, ColorReset, args)
 	}
 }
+
+// LogQueryContext logs a SQL query with the request ID of ctx
+func LogQueryContext(ctx context.Context, query string, args ...interface{}) {
+	id := RequestID(ctx)
+	if id == "" {
+		LogQuery(query, args...)
+		return
+	}
+	log.Printf("%s[SQL]%s %s[%s]%s %s%s%s", ColorYellow, ColorReset, ColorCyan, id, ColorReset, ColorYellow, query, ColorReset)
+	if len(args) > 0 {
+		log.Printf("%s[SQL Args]%s %s[%s]%s %v", ColorCyan, ColorReset, ColorCyan, id, ColorReset, args)
+	}
+}
+
+// LogQueryErrorContext logs a SQL query error with the request ID of ctx
+func LogQueryErrorContext(ctx context.Context, query string, err error, args ...interface{}) {
+	id := RequestID(ctx)
+	if id == "" {
+		LogQueryError(query, err, args...)
+		return
+	}
+	log.Printf("%s[SQL ERROR]%s %s[%s]%s %s%s%s", ColorRed, ColorReset, ColorCyan, id, ColorReset, ColorYellow, query, ColorReset)
+	log.Printf("%s[SQL Error]%s %s[%s]%s %v", ColorRed, ColorReset, ColorCyan, id, ColorReset, err)
+	if len(args) > 0 {
+		log.Printf("%s[SQL Args]%s %s[%s]%s %v", ColorCyan, ColorReset, ColorCyan, id, ColorReset, args)
+	}
+}
@@ -0,0 +1,193 @@
+package mail
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"sync"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/worker"
+)
+
+// DeliverHandler is the name of the built-in worker handler that delivers
+// queued messages
+const DeliverHandler = "rebolo.mail.deliver"
+
+// MaxDeliveryAttempts is how many times a queued message is tried before
+// it's dropped
+const MaxDeliveryAttempts = 5
+
+// attemptArg is the job Args key counting delivery attempts
+const attemptArg = "attempt"
+
+// retryDelay is the wait after the first failed attempt, doubled after
+// each one (1m, 2m, 4m, 8m)
+var retryDelay = time.Minute
+
+var (
+	queue   worker.Worker
+	queueMu sync.RWMutex
+)
+
+// RegisterWorker registers the built-in delivery handler on w and makes w the
+// queue used by DeliverLater. Queued messages are sent with mailer. Failed
+// deliveries are enqueued again with w.PerformIn, up to MaxDeliveryAttempts
+// with a doubling delay, unless the server rejected the message for good
+// (5xx) or rejected some recipients. Retries waiting in the Simple worker
+// are lost on restart.
+func RegisterWorker(w worker.Worker, mailer *Mailer) error {
+	err := w.Register(DeliverHandler, func(args worker.Args) error {
+		msg, err := decodeJob(args)
+		if err != nil {
+			return err
+		}
+		err = mailer.Send(msg)
+		if err == nil {
+			return nil
+		}
+
+		attempt := deliveryAttempt(args)
+		if attempt >= MaxDeliveryAttempts || isPermanent(err) {
+			return fmt.Errorf("mail delivery failed after %d attempts, giving up: %w", attempt, err)
+		}
+		retry := worker.Job{Queue: "mail", Handler: DeliverHandler, Args: make(worker.Args, len(args))}
+		for k, v := range args {
+			retry.Args[k] = v
+		}
+		retry.Args[attemptArg] = attempt + 1
+		delay := retryDelay << (attempt - 1)
+		if retryErr := w.PerformIn(retry, delay); retryErr != nil {
+			return fmt.Errorf("mail delivery attempt %d failed and can't be retried (%v): %w", attempt, retryErr, err)
+		}
+		return fmt.Errorf("mail delivery attempt %d failed, retrying in %s: %w", attempt, delay, err)
+	})
+	if err != nil {
+		return err
+	}
+
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	queue = w
+	return nil
+}
+
+// DeliverLater enqueues msg to be sent by the background worker so the
+// request doesn't wait on the mail server
+func DeliverLater(msg *Message) error {
+	queueMu.RLock()
+	w := queue
+	queueMu.RUnlock()
+
+	if w == nil {
+		return fmt.Errorf("mail worker not registered (see mail.RegisterWorker)")
+	}
+
+	job, err := NewDeliveryJob(msg)
+	if err != nil {
+		return err
+	}
+	return w.Perform(job)
+}
+
+// DeliverLater renders the named mailer now and enqueues it for delivery
+func (m *Mailer) DeliverLater(name string, data interface{}, to ...string) error {
+	msg, err := m.Render(name, data)
+	if err != nil {
+		return err
+	}
+	for _, addr := range to {
+		msg.AddTo(addr)
+	}
+	return DeliverLater(msg)
+}
+
+// NewDeliveryJob serializes msg into a job for the built-in delivery handler
+func NewDeliveryJob(msg *Message) (worker.Job, error) {
+	msg.mu.Lock()
+	data, err := json.Marshal(jobMessage{
+		From:        msg.From,
+		To:          msg.To,
+		Cc:          msg.Cc,
+		Bcc:         msg.Bcc,
+		Subject:     msg.Subject,
+		Body:        msg.Body,
+		HTMLBody:    msg.HTMLBody,
+		Headers:     msg.Headers,
+		Attachments: msg.Attachments,
+	})
+	msg.mu.Unlock()
+	if err != nil {
+		return worker.Job{}, fmt.Errorf("failed to serialize message: %w", err)
+	}
+
+	return worker.Job{
+		Queue:   "mail",
+		Handler: DeliverHandler,
+		Args:    worker.Args{"message": string(data)},
+	}, nil
+}
+
+// jobMessage is the JSON form of a Message stored in job args
+type jobMessage struct {
+	From        string            `json:"from"`
+	To          []string          `json:"to"`
+	Cc          []string          `json:"cc,omitempty"`
+	Bcc         []string          `json:"bcc,omitempty"`
+	Subject     string            `json:"subject"`
+	Body        string            `json:"body,omitempty"`
+	HTMLBody    string            `json:"html_body,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+}
+
+// deliveryAttempt returns the attempt number of a delivery job, starting
+// at 1. Workers serializing Args as JSON turn it into a float64.
+func deliveryAttempt(args worker.Args) int {
+	switch n := args[attemptArg].(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 1
+}
+
+// isPermanent reports whether the delivery must not be tried again: the
+// server refused the message for good, or accepted it for some recipients
+// only, who would get it twice
+func isPermanent(err error) bool {
+	var rcptErr *RecipientError
+	if errors.As(err, &rcptErr) {
+		return true
+	}
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code >= 500
+}
+
+func decodeJob(args worker.Args) (*Message, error) {
+	data, ok := args["message"].(string)
+	if !ok {
+		return nil, fmt.Errorf("mail job has no message")
+	}
+
+	var jm jobMessage
+	if err := json.Unmarshal([]byte(data), &jm); err != nil {
+		return nil, fmt.Errorf("failed to deserialize message: %w", err)
+	}
+
+	msg := NewMessage()
+	msg.From = jm.From
+	msg.To = jm.To
+	msg.Cc = jm.Cc
+	msg.Bcc = jm.Bcc
+	msg.Subject = jm.Subject
+	msg.Body = jm.Body
+	msg.HTMLBody = jm.HTMLBody
+	msg.Attachments = jm.Attachments
+	for key, value := range jm.Headers {
+		msg.Headers[key] = value
+	}
+	return msg, nil
+}
@@ -0,0 +1,138 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/worker"
+)
+
+// flakySender fails with the queued errors before succeeding
+type flakySender struct {
+	errs  []error
+	calls int
+}
+
+func (s *flakySender) Send(msg *Message) error {
+	s.calls++
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+// inlineWorker runs jobs right away and records the PerformIn delays
+type inlineWorker struct {
+	handlers map[string]worker.Handler
+	delays   []time.Duration
+	errs     []error
+}
+
+func (w *inlineWorker) Start(context.Context) error { return nil }
+func (w *inlineWorker) Stop() error                 { return nil }
+
+func (w *inlineWorker) Register(name string, h worker.Handler) error {
+	w.handlers[name] = h
+	return nil
+}
+
+func (w *inlineWorker) Perform(job worker.Job) error {
+	if err := w.handlers[job.Handler](job.Args); err != nil {
+		w.errs = append(w.errs, err)
+	}
+	return nil
+}
+
+func (w *inlineWorker) PerformAt(job worker.Job, t time.Time) error {
+	return w.PerformIn(job, time.Until(t))
+}
+
+func (w *inlineWorker) PerformIn(job worker.Job, d time.Duration) error {
+	w.delays = append(w.delays, d)
+	return w.Perform(job)
+}
+
+func TestDeliverLaterRetries(t *testing.T) {
+	temporary := &textproto.Error{Code: 421, Msg: "try again later"}
+	permanent := &textproto.Error{Code: 550, Msg: "no such user"}
+	partial := &RecipientError{Rejected: map[string]error{"b@example.com": permanent}}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantDelay []time.Duration
+		wantFail  bool
+	}{
+		{"delivered", nil, 1, nil, false},
+		{"temporary failure", []error{temporary, temporary}, 3, []time.Duration{time.Minute, 2 * time.Minute}, false},
+		{"connection error", []error{errors.New("connection refused")}, 2, []time.Duration{time.Minute}, false},
+		{"permanent failure", []error{permanent}, 1, nil, true},
+		{"rejected recipients", []error{partial}, 1, nil, true},
+		{
+			"gives up",
+			[]error{temporary, temporary, temporary, temporary, temporary, temporary},
+			MaxDeliveryAttempts,
+			[]time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &flakySender{errs: tt.errs}
+			w := &inlineWorker{handlers: map[string]worker.Handler{}}
+			if err := RegisterWorker(w, NewMailer(sender, "app@example.com", "")); err != nil {
+				t.Fatal(err)
+			}
+
+			msg := NewMessage()
+			msg.From = "app@example.com"
+			msg.AddTo("a@example.com")
+			msg.Subject = "Hello"
+			msg.Body = "Hi"
+			if err := DeliverLater(msg); err != nil {
+				t.Fatal(err)
+			}
+
+			if sender.calls != tt.wantCalls {
+				t.Errorf("sent %d times, want %d", sender.calls, tt.wantCalls)
+			}
+			if len(w.delays) != len(tt.wantDelay) {
+				t.Fatalf("retried after %v, want %v", w.delays, tt.wantDelay)
+			}
+			for i, d := range tt.wantDelay {
+				if w.delays[i] != d {
+					t.Errorf("retry %d after %s, want %s", i+1, w.delays[i], d)
+				}
+			}
+
+			// Every failed attempt is reported; only the last one gives up
+			failed := len(w.errs) == len(tt.wantDelay)+1
+			if failed != tt.wantFail {
+				t.Errorf("gave up = %v, want %v (errors: %v)", failed, tt.wantFail, w.errs)
+			}
+		})
+	}
+}
+
+func TestDeliveryAttempt(t *testing.T) {
+	tests := []struct {
+		args worker.Args
+		want int
+	}{
+		{worker.Args{}, 1},
+		{worker.Args{attemptArg: 3}, 3},
+		{worker.Args{attemptArg: float64(4)}, 4}, // Args round-tripped through JSON
+	}
+	for _, tt := range tests {
+		if got := deliveryAttempt(tt.args); got != tt.want {
+			t.Errorf("deliveryAttempt(%v) = %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
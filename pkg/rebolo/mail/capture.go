@@ -0,0 +1,106 @@
+package mail
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// CapturedMessage is a message recorded by a CaptureSender
+type CapturedMessage struct {
+	ID      string
+	SentAt  time.Time
+	Message *Message
+	Raw     []byte // Raw MIME as built at send time
+	Err     error  // Error returned by the wrapped sender, if any
+}
+
+// CaptureSender records every message and forwards it to another sender.
+// It backs the development mail preview at /__rebolo__/mail.
+type CaptureSender struct {
+	next     Sender
+	limit    int
+	messages []*CapturedMessage
+	mu       sync.RWMutex
+}
+
+// NewCaptureSender creates a sender that keeps the last limit messages and
+// forwards them to next (which may be nil to only capture)
+func NewCaptureSender(next Sender, limit int) *CaptureSender {
+	if limit <= 0 {
+		limit = 100
+	}
+	return &CaptureSender{
+		next:     next,
+		limit:    limit,
+		messages: make([]*CapturedMessage, 0),
+	}
+}
+
+// Next returns the wrapped sender
+func (s *CaptureSender) Next() Sender {
+	return s.next
+}
+
+// Send records the message and forwards it to the wrapped sender
+func (s *CaptureSender) Send(msg *Message) error {
+	raw, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	b := make([]byte, 8)
+	rand.Read(b)
+	captured := &CapturedMessage{
+		ID:      hex.EncodeToString(b),
+		SentAt:  time.Now(),
+		Message: msg,
+		Raw:     raw,
+	}
+
+	if s.next != nil {
+		captured.Err = s.next.Send(msg)
+	}
+
+	s.mu.Lock()
+	s.messages = append(s.messages, captured)
+	if len(s.messages) > s.limit {
+		s.messages = s.messages[len(s.messages)-s.limit:]
+	}
+	s.mu.Unlock()
+
+	return captured.Err
+}
+
+// Messages returns captured messages, most recent first
+func (s *CaptureSender) Messages() []*CapturedMessage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*CapturedMessage, 0, len(s.messages))
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		result = append(result, s.messages[i])
+	}
+	return result
+}
+
+// Get returns a captured message by ID
+func (s *CaptureSender) Get(id string) (*CapturedMessage, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, captured := range s.messages {
+		if captured.ID == id {
+			return captured, true
+		}
+	}
+	return nil, false
+}
+
+// Clear removes all captured messages
+func (s *CaptureSender) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = make([]*CapturedMessage, 0)
+}
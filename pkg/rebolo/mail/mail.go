@@ -2,10 +2,7 @@ package mail
 
 import (
 	"bytes"
-	"fmt"
 	"io"
-	"net/smtp"
-	"strings"
 	"sync"
 )
 
@@ -20,6 +17,7 @@ type Message struct {
 	HTMLBody    string
 	Headers     map[string]string
 	Attachments []Attachment
+	signer      *DKIMSigner // Set by DKIMSender, applied by Bytes
 	mu          sync.Mutex
 }
 
@@ -120,127 +118,6 @@ type Sender interface {
 	Send(*Message) error
 }
 
-// SMTPConfig holds SMTP configuration
-type SMTPConfig struct {
-	Host     string
-	Port     int
-	Username string
-	Password string
-	Auth     smtp.Auth
-}
-
-// SMTPSender implements Sender using SMTP
-type SMTPSender struct {
-	config SMTPConfig
-}
-
-// NewSMTPSender creates a new SMTP sender
-func NewSMTPSender(host string, port int, username, password string) *SMTPSender {
-	auth := smtp.PlainAuth("", username, password, host)
-	return &SMTPSender{
-		config: SMTPConfig{
-			Host:     host,
-			Port:     port,
-			Username: username,
-			Password: password,
-			Auth:     auth,
-		},
-	}
-}
-
-// Send sends an email message via SMTP
-func (s *SMTPSender) Send(msg *Message) error {
-	if msg.From == "" {
-		return fmt.Errorf("from address is required")
-	}
-	if len(msg.To) == 0 {
-		return fmt.Errorf("at least one recipient is required")
-	}
-
-	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
-
-	// Build email body
-	body, err := s.buildBody(msg)
-	if err != nil {
-		return err
-	}
-
-	// Build headers
-	headers := s.buildHeaders(msg)
-
-	// Combine headers and body
-	email := append(headers, body...)
-
-	// Send email
-	recipients := append(msg.To, append(msg.Cc, msg.Bcc...)...)
-	return smtp.SendMail(addr, s.config.Auth, msg.From, recipients, email)
-}
-
-func (s *SMTPSender) buildHeaders(msg *Message) []byte {
-	var buf bytes.Buffer
-
-	buf.WriteString(fmt.Sprintf("From: %s\r\n", msg.From))
-	buf.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(msg.To, ", ")))
-
-	if len(msg.Cc) > 0 {
-		buf.WriteString(fmt.Sprintf("Cc: %s\r\n", strings.Join(msg.Cc, ", ")))
-	}
-
-	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", msg.Subject))
-	buf.WriteString("MIME-Version: 1.0\r\n")
-
-	// Add custom headers
-	for key, value := range msg.Headers {
-		buf.WriteString(fmt.Sprintf("%s: %s\r\n", key, value))
-	}
-
-	return buf.Bytes()
-}
-
-func (s *SMTPSender) buildBody(msg *Message) ([]byte, error) {
-	var buf bytes.Buffer
-
-	// Simple multipart if we have both text and HTML
-	if msg.HTMLBody != "" && msg.Body != "" {
-		boundary := "----=_Part_0_" + generateBoundary()
-
-		buf.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=%s\r\n", boundary))
-		buf.WriteString("\r\n")
-
-		// Text part
-		buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-		buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
-		buf.WriteString("\r\n")
-		buf.WriteString(msg.Body)
-		buf.WriteString("\r\n")
-
-		// HTML part
-		buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-		buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
-		buf.WriteString("\r\n")
-		buf.WriteString(msg.HTMLBody)
-		buf.WriteString("\r\n")
-
-		buf.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
-	} else if msg.HTMLBody != "" {
-		buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
-		buf.WriteString("\r\n")
-		buf.WriteString(msg.HTMLBody)
-	} else {
-		buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
-		buf.WriteString("\r\n")
-		buf.WriteString(msg.Body)
-	}
-
-	buf.WriteString("\r\n")
-
-	return buf.Bytes(), nil
-}
-
-func generateBoundary() string {
-	return fmt.Sprintf("%d", len("boundary"))
-}
-
 // ReadAttachment reads an attachment from an io.Reader
 func ReadAttachment(name, contentType string, r io.Reader) ([]byte, error) {
 	var buf bytes.Buffer
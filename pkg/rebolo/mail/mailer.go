@@ -0,0 +1,327 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// Provider is implemented by applications that expose a Mailer
+type Provider interface {
+	Mailer() *Mailer
+}
+
+// Deliver renders the named mailer with data and sends it to the recipients
+// Usage: mail.Deliver(app, "welcome", data, "user@example.com")
+func Deliver(app Provider, name string, data interface{}, to ...string) error {
+	mailer := app.Mailer()
+	if mailer == nil {
+		return fmt.Errorf("mailer not initialized")
+	}
+	return mailer.Deliver(name, data, to...)
+}
+
+// URLResolver resolves a named route to a path (e.g. Application.URLFor)
+type URLResolver func(name string, params map[string]string) (string, error)
+
+// Mailer renders emails from views/mailers and delivers them with a Sender.
+//
+// For a mailer named "welcome" it looks for views/mailers/welcome.html and
+// views/mailers/welcome.txt; at least one of them must exist. Both are wrapped
+// by views/layouts/mailer.html and views/layouts/mailer.txt when present, which
+// render the mailer body with {{template "content" .}}. The subject comes from
+// a {{define "subject"}} block in either template.
+type Mailer struct {
+	Sender  Sender
+	From    string
+	BaseURL string // Used to build absolute URLs (e.g. http://localhost:3000)
+
+	viewsDir string
+	resolver URLResolver
+	funcs    map[string]interface{}
+	html     map[string]*htmltemplate.Template
+	text     map[string]*texttemplate.Template
+	loaded   bool
+	mu       sync.RWMutex
+}
+
+// NewMailer creates a new mailer that loads templates from the views directory
+func NewMailer(sender Sender, from, baseURL string) *Mailer {
+	m := &Mailer{
+		Sender:   sender,
+		From:     from,
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		viewsDir: "views",
+	}
+	m.funcs = m.defaultHelpers()
+	return m
+}
+
+// SetViewsDir changes the directory templates are loaded from
+func (m *Mailer) SetViewsDir(dir string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.viewsDir = dir
+	m.loaded = false
+}
+
+// SetURLResolver sets the route resolver used by the url_for helper
+func (m *Mailer) SetURLResolver(resolver URLResolver) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resolver = resolver
+}
+
+// AddHelper registers a template helper available in mailer templates
+func (m *Mailer) AddHelper(name string, fn interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.funcs[name] = fn
+	m.loaded = false
+}
+
+// Reload discards parsed templates so they are loaded again on next render
+func (m *Mailer) Reload() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loaded = false
+}
+
+// URLFor returns the absolute URL for a named route
+func (m *Mailer) URLFor(name string, params map[string]string) (string, error) {
+	m.mu.RLock()
+	resolver := m.resolver
+	m.mu.RUnlock()
+
+	if resolver == nil {
+		return "", fmt.Errorf("no URL resolver configured for mailer")
+	}
+
+	path, err := resolver(name, params)
+	if err != nil {
+		return "", err
+	}
+	return m.AbsoluteURL(path), nil
+}
+
+// AbsoluteURL prefixes a path with the mailer BaseURL
+func (m *Mailer) AbsoluteURL(path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return m.BaseURL + path
+}
+
+// Render renders the named mailer into a message without sending it
+func (m *Mailer) Render(name string, data interface{}) (*Message, error) {
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	htmlTmpl := m.html[name]
+	textTmpl := m.text[name]
+	m.mu.RUnlock()
+
+	if htmlTmpl == nil && textTmpl == nil {
+		return nil, fmt.Errorf("mailer template not found: %s (looked for %s/mailers/%s.html and .txt)", name, m.viewsDir, name)
+	}
+
+	msg := NewMessage().SetFrom(m.From)
+	subject := ""
+
+	if htmlTmpl != nil {
+		var buf bytes.Buffer
+		if err := htmlTmpl.ExecuteTemplate(&buf, htmlTmpl.Name(), data); err != nil {
+			return nil, fmt.Errorf("failed to render %s.html: %w", name, err)
+		}
+		msg.SetHTMLBody(buf.String())
+
+		if t := htmlTmpl.Lookup("subject"); t != nil {
+			buf.Reset()
+			if err := t.Execute(&buf, data); err != nil {
+				return nil, fmt.Errorf("failed to render subject for %s: %w", name, err)
+			}
+			subject = html.UnescapeString(buf.String())
+		}
+	}
+
+	if textTmpl != nil {
+		var buf bytes.Buffer
+		if err := textTmpl.ExecuteTemplate(&buf, textTmpl.Name(), data); err != nil {
+			return nil, fmt.Errorf("failed to render %s.txt: %w", name, err)
+		}
+		msg.SetBody(buf.String())
+
+		// The text subject wins since it needs no HTML unescaping
+		if t := textTmpl.Lookup("subject"); t != nil {
+			buf.Reset()
+			if err := t.Execute(&buf, data); err != nil {
+				return nil, fmt.Errorf("failed to render subject for %s: %w", name, err)
+			}
+			subject = buf.String()
+		}
+	}
+
+	msg.SetSubject(strings.TrimSpace(subject))
+	return msg, nil
+}
+
+// Deliver renders the named mailer and sends it to the recipients
+func (m *Mailer) Deliver(name string, data interface{}, to ...string) error {
+	msg, err := m.Render(name, data)
+	if err != nil {
+		return err
+	}
+	for _, addr := range to {
+		msg.AddTo(addr)
+	}
+	return m.Send(msg)
+}
+
+// Send sends a message with the configured sender, filling in the default From
+func (m *Mailer) Send(msg *Message) error {
+	if m.Sender == nil {
+		return fmt.Errorf("no mail sender configured")
+	}
+	if msg.From == "" {
+		msg.SetFrom(m.From)
+	}
+	return m.Sender.Send(msg)
+}
+
+// defaultHelpers returns the helpers available in every mailer template
+func (m *Mailer) defaultHelpers() map[string]interface{} {
+	return map[string]interface{}{
+		"url_for": func(name string, pairs ...string) (string, error) {
+			if len(pairs)%2 != 0 {
+				return "", fmt.Errorf("url_for expects key/value pairs")
+			}
+			params := make(map[string]string, len(pairs)/2)
+			for i := 0; i < len(pairs); i += 2 {
+				params[pairs[i]] = pairs[i+1]
+			}
+			return m.URLFor(name, params)
+		},
+		"absolute_url": m.AbsoluteURL,
+		"lower":        strings.ToLower,
+		"upper":        strings.ToUpper,
+		"title":        func(s string) string { return cases.Title(language.English).String(s) },
+	}
+}
+
+// load parses the mailer templates once (until Reload is called)
+func (m *Mailer) load() error {
+	m.mu.RLock()
+	loaded := m.loaded
+	m.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.loaded {
+		return nil
+	}
+
+	htmlLayout, _ := os.ReadFile(filepath.Join(m.viewsDir, "layouts", "mailer.html"))
+	textLayout, _ := os.ReadFile(filepath.Join(m.viewsDir, "layouts", "mailer.txt"))
+
+	htmlTemplates := make(map[string]*htmltemplate.Template)
+	textTemplates := make(map[string]*texttemplate.Template)
+
+	mailersDir := filepath.Join(m.viewsDir, "mailers")
+	err := filepath.Walk(mailersDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == mailersDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		ext := filepath.Ext(path)
+		if ext != ".html" && ext != ".txt" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		// e.g. "views/mailers/users/welcome.html" -> "users/welcome"
+		rel, err := filepath.Rel(mailersDir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(strings.TrimSuffix(rel, ext))
+
+		// Each mailer gets its own template set so "content" and "subject"
+		// blocks don't collide between mailers
+		if ext == ".html" {
+			t, err := parseHTMLMailer(m.funcs, string(content), htmlLayout)
+			if err != nil {
+				log.Printf("⚠️ Failed to parse %s: %v", path, err)
+				return err
+			}
+			htmlTemplates[name] = t
+		} else {
+			t, err := parseTextMailer(m.funcs, string(content), textLayout)
+			if err != nil {
+				log.Printf("⚠️ Failed to parse %s: %v", path, err)
+				return err
+			}
+			textTemplates[name] = t
+		}
+
+		log.Printf("   ✓ Loaded mailer: %s (name: %s)", path, name)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load mailer templates: %w", err)
+	}
+
+	m.html = htmlTemplates
+	m.text = textTemplates
+	m.loaded = true
+	return nil
+}
+
+func parseHTMLMailer(funcs map[string]interface{}, content string, layout []byte) (*htmltemplate.Template, error) {
+	t, err := htmltemplate.New("content").Funcs(funcs).Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	if layout == nil {
+		return t, nil
+	}
+	return t.New("layout").Parse(string(layout))
+}
+
+func parseTextMailer(funcs map[string]interface{}, content string, layout []byte) (*texttemplate.Template, error) {
+	t, err := texttemplate.New("content").Funcs(funcs).Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	if layout == nil {
+		return t, nil
+	}
+	return t.New("layout").Parse(string(layout))
+}
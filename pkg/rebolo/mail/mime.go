@@ -0,0 +1,261 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bytes builds the complete RFC 5322 message, including MIME parts for the
+// text body, HTML body and attachments
+func (m *Message) Bytes() ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var buf bytes.Buffer
+	writeHeaders(&buf, m)
+
+	if err := writeBody(&buf, m); err != nil {
+		return nil, err
+	}
+
+	if m.signer != nil {
+		return m.signer.Sign(buf.Bytes())
+	}
+	return buf.Bytes(), nil
+}
+
+// Recipients returns every envelope recipient (To, Cc and Bcc)
+func (m *Message) Recipients() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	recipients = append(recipients, m.To...)
+	recipients = append(recipients, m.Cc...)
+	recipients = append(recipients, m.Bcc...)
+	return recipients
+}
+
+func writeHeaders(buf *bytes.Buffer, m *Message) {
+	headers := map[string]string{
+		"From":         m.From,
+		"To":           strings.Join(m.To, ", "),
+		"Subject":      mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date":         time.Now().Format(time.RFC1123Z),
+		"Message-Id":   generateMessageID(m.From),
+		"Mime-Version": "1.0",
+	}
+	if len(m.Cc) > 0 {
+		headers["Cc"] = strings.Join(m.Cc, ", ")
+	}
+
+	// Custom headers override the generated ones (e.g. a fixed Message-ID)
+	for key, value := range m.Headers {
+		headers[textproto.CanonicalMIMEHeaderKey(key)] = value
+	}
+
+	// Write the standard headers first and in a stable order
+	order := []string{"From", "To", "Cc", "Subject", "Date", "Message-Id", "Mime-Version"}
+	written := make(map[string]bool, len(order))
+	for _, key := range order {
+		if value, ok := headers[key]; ok {
+			fmt.Fprintf(buf, "%s: %s\r\n", headerName(key), value)
+			written[key] = true
+		}
+	}
+
+	extra := make([]string, 0, len(headers))
+	for key := range headers {
+		if !written[key] {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+	for _, key := range extra {
+		fmt.Fprintf(buf, "%s: %s\r\n", key, headers[key])
+	}
+}
+
+// headerName restores the conventional spelling of headers that
+// CanonicalMIMEHeaderKey lowercases
+func headerName(key string) string {
+	switch key {
+	case "Message-Id":
+		return "Message-ID"
+	case "Mime-Version":
+		return "MIME-Version"
+	}
+	return key
+}
+
+// entity is a MIME entity: its own headers plus an encoded body
+type entity struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+func writeBody(buf *bytes.Buffer, m *Message) error {
+	e, err := buildEntity(m)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(e.header))
+	for key := range e.header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(buf, "%s: %s\r\n", key, e.header.Get(key))
+	}
+	buf.WriteString("\r\n")
+	buf.Write(e.body)
+	return nil
+}
+
+// buildEntity nests the message content as multipart/mixed (attachments),
+// multipart/related (embedded files) and multipart/alternative (text + HTML)
+func buildEntity(m *Message) (*entity, error) {
+	var attachments, inline []*entity
+	for _, a := range m.Attachments {
+		if a.Embedded {
+			inline = append(inline, attachmentEntity(a, "inline"))
+		} else {
+			attachments = append(attachments, attachmentEntity(a, "attachment"))
+		}
+	}
+
+	content, err := contentEntity(m)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(inline) > 0 {
+		content, err = multipartEntity("related", append([]*entity{content}, inline...))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(attachments) > 0 {
+		return multipartEntity("mixed", append([]*entity{content}, attachments...))
+	}
+
+	return content, nil
+}
+
+// contentEntity builds the text and/or HTML bodies, using
+// multipart/alternative when both are present
+func contentEntity(m *Message) (*entity, error) {
+	if m.HTMLBody != "" && m.Body != "" {
+		text, err := textEntity("text/plain", m.Body)
+		if err != nil {
+			return nil, err
+		}
+		html, err := textEntity("text/html", m.HTMLBody)
+		if err != nil {
+			return nil, err
+		}
+		return multipartEntity("alternative", []*entity{text, html})
+	}
+
+	if m.HTMLBody != "" {
+		return textEntity("text/html", m.HTMLBody)
+	}
+	return textEntity("text/plain", m.Body)
+}
+
+func textEntity(contentType, body string) (*entity, error) {
+	var buf bytes.Buffer
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\r\n")
+
+	return &entity{
+		header: textproto.MIMEHeader{
+			"Content-Type":              {contentType + "; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		},
+		body: buf.Bytes(),
+	}, nil
+}
+
+func attachmentEntity(a Attachment, disposition string) *entity {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType(contentType, map[string]string{"name": a.Name})},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType(disposition, map[string]string{"filename": a.Name})},
+	}
+	if disposition == "inline" {
+		header.Set("Content-ID", "<"+a.Name+">")
+	}
+
+	// Wrap base64 output at 76 characters as required by RFC 2045
+	var buf bytes.Buffer
+	encoded := base64.StdEncoding.EncodeToString(a.Data)
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+
+	return &entity{header: header, body: buf.Bytes()}
+}
+
+func multipartEntity(subtype string, parts []*entity) (*entity, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	for _, p := range parts {
+		part, err := w.CreatePart(p.header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(p.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\r\n")
+
+	return &entity{
+		header: textproto.MIMEHeader{
+			"Content-Type": {fmt.Sprintf("multipart/%s; boundary=%s", subtype, w.Boundary())},
+		},
+		body: buf.Bytes(),
+	}, nil
+}
+
+// generateMessageID creates a unique Message-ID using the sender's domain
+func generateMessageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at != -1 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
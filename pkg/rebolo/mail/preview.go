@@ -0,0 +1,238 @@
+package mail
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	previews   = make(map[string]func() *Message)
+	previewsMu sync.RWMutex
+)
+
+// Preview registers a function that builds a sample message for the
+// development mail preview UI. Nothing is sent when a preview is rendered.
+// Usage:
+//
+//	mail.Preview("welcome", func() *mail.Message {
+//		msg, _ := app.Mailer().Render("welcome", sampleUser)
+//		return msg.AddTo("user@example.com")
+//	})
+func Preview(name string, fn func() *Message) {
+	previewsMu.Lock()
+	defer previewsMu.Unlock()
+
+	if _, exists := previews[name]; exists {
+		panic(fmt.Sprintf("mail preview %s already registered", name))
+	}
+	previews[name] = fn
+}
+
+// Previews returns the registered preview names sorted by name
+func Previews() []string {
+	previewsMu.RLock()
+	defer previewsMu.RUnlock()
+
+	names := make([]string, 0, len(previews))
+	for name := range previews {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RenderPreview builds the message for a registered preview
+func RenderPreview(name string) (*Message, error) {
+	previewsMu.RLock()
+	fn, ok := previews[name]
+	previewsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("mail preview %s not found", name)
+	}
+
+	msg := fn()
+	if msg == nil {
+		return nil, fmt.Errorf("mail preview %s returned no message", name)
+	}
+	return msg, nil
+}
+
+// PreviewHandler serves the development mail preview UI:
+//
+//	{prefix}                          list of captured messages and previews
+//	{prefix}/messages/{id}            captured message (HTML, text and raw tabs)
+//	{prefix}/messages/{id}/html|text|raw
+//	{prefix}/previews/{name}          registered preview
+//	{prefix}/previews/{name}/html|text|raw
+//	{prefix}/clear (POST)             remove captured messages
+type PreviewHandler struct {
+	capture *CaptureSender
+	prefix  string
+}
+
+// NewPreviewHandler creates the preview UI handler mounted at prefix
+func NewPreviewHandler(capture *CaptureSender, prefix string) *PreviewHandler {
+	return &PreviewHandler{
+		capture: capture,
+		prefix:  strings.TrimRight(prefix, "/"),
+	}
+}
+
+// ServeHTTP routes preview UI requests
+func (h *PreviewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, h.prefix)
+	path = strings.TrimRight(path, "/")
+
+	switch {
+	case path == "":
+		h.index(w)
+	case path == "/clear" && r.Method == http.MethodPost:
+		h.capture.Clear()
+		http.Redirect(w, r, h.prefix, http.StatusSeeOther)
+	case strings.HasPrefix(path, "/messages/"):
+		id, part := splitPart(strings.TrimPrefix(path, "/messages/"))
+		captured, ok := h.capture.Get(id)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		h.show(w, captured.Message, captured.Raw, part, h.prefix+"/messages/"+id, captured.SentAt, captured.Err)
+	case strings.HasPrefix(path, "/previews/"):
+		name, part := splitPart(strings.TrimPrefix(path, "/previews/"))
+		msg, err := RenderPreview(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		raw, err := msg.Bytes()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.show(w, msg, raw, part, h.prefix+"/previews/"+name, time.Now(), nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// splitPart splits "id/html" into ("id", "html")
+func splitPart(path string) (string, string) {
+	for _, part := range []string{"html", "text", "raw"} {
+		if strings.HasSuffix(path, "/"+part) {
+			return strings.TrimSuffix(path, "/"+part), part
+		}
+	}
+	return path, ""
+}
+
+func (h *PreviewHandler) index(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	previewIndexTemplate.Execute(w, map[string]interface{}{
+		"Prefix":   h.prefix,
+		"Messages": h.capture.Messages(),
+		"Previews": Previews(),
+	})
+}
+
+func (h *PreviewHandler) show(w http.ResponseWriter, msg *Message, raw []byte, part, base string, sentAt time.Time, sendErr error) {
+	switch part {
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(msg.HTMLBody))
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(msg.Body))
+	case "raw":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(raw)
+	default:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		previewShowTemplate.Execute(w, map[string]interface{}{
+			"Prefix":  h.prefix,
+			"Base":    base,
+			"Message": msg,
+			"SentAt":  sentAt,
+			"Err":     sendErr,
+			"Raw":     string(raw),
+		})
+	}
+}
+
+const previewStyles = `
+<style>
+	body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; margin: 0; background: #f5f5f7; color: #333; }
+	header { background: #764ba2; color: white; padding: 16px 24px; }
+	header a { color: white; text-decoration: none; }
+	main { padding: 24px; }
+	table { width: 100%; border-collapse: collapse; background: white; }
+	th, td { text-align: left; padding: 8px 12px; border-bottom: 1px solid #eee; }
+	.error { color: #c0392b; }
+	.tabs a { margin-right: 16px; }
+	iframe { width: 100%; height: 70vh; border: 1px solid #ddd; background: white; }
+	pre { background: white; padding: 12px; border: 1px solid #ddd; overflow: auto; }
+</style>
+`
+
+var previewIndexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="UTF-8"><title>Mail preview - Rebolo</title>` + previewStyles + `</head>
+<body>
+<header><a href="{{.Prefix}}">📧 Rebolo mail preview</a></header>
+<main>
+	<h2>Previews</h2>
+	{{if .Previews}}<ul>{{range .Previews}}<li><a href="{{$.Prefix}}/previews/{{.}}">{{.}}</a></li>{{end}}</ul>
+	{{else}}<p>No previews registered. Use <code>mail.Preview("name", func() *mail.Message {...})</code>.</p>{{end}}
+
+	<h2>Sent messages</h2>
+	{{if .Messages}}
+	<form method="POST" action="{{.Prefix}}/clear"><button type="submit">Clear</button></form>
+	<table>
+		<tr><th>Sent</th><th>To</th><th>Subject</th><th></th></tr>
+		{{range .Messages}}
+		<tr>
+			<td>{{.SentAt.Format "2006-01-02 15:04:05"}}</td>
+			<td>{{range $i, $to := .Message.To}}{{if $i}}, {{end}}{{$to}}{{end}}</td>
+			<td><a href="{{$.Prefix}}/messages/{{.ID}}">{{.Message.Subject}}</a></td>
+			<td>{{if .Err}}<span class="error">{{.Err}}</span>{{end}}</td>
+		</tr>
+		{{end}}
+	</table>
+	{{else}}<p>No messages sent yet.</p>{{end}}
+</main>
+</body>
+</html>`))
+
+var previewShowTemplate = template.Must(template.New("show").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="UTF-8"><title>{{.Message.Subject}} - Mail preview</title>` + previewStyles + `</head>
+<body>
+<header><a href="{{.Prefix}}">📧 Rebolo mail preview</a></header>
+<main>
+	<table>
+		<tr><th>From</th><td>{{.Message.From}}</td></tr>
+		<tr><th>To</th><td>{{range $i, $to := .Message.To}}{{if $i}}, {{end}}{{$to}}{{end}}</td></tr>
+		{{if .Message.Cc}}<tr><th>Cc</th><td>{{range $i, $cc := .Message.Cc}}{{if $i}}, {{end}}{{$cc}}{{end}}</td></tr>{{end}}
+		{{if .Message.Bcc}}<tr><th>Bcc</th><td>{{range $i, $bcc := .Message.Bcc}}{{if $i}}, {{end}}{{$bcc}}{{end}}</td></tr>{{end}}
+		<tr><th>Subject</th><td>{{.Message.Subject}}</td></tr>
+		<tr><th>Date</th><td>{{.SentAt.Format "2006-01-02 15:04:05"}}</td></tr>
+		{{range .Message.Attachments}}<tr><th>Attachment</th><td>{{.Name}} ({{.ContentType}})</td></tr>{{end}}
+		{{if .Err}}<tr><th>Error</th><td class="error">{{.Err}}</td></tr>{{end}}
+	</table>
+	<p class="tabs">
+		{{if .Message.HTMLBody}}<a href="{{.Base}}/html" target="_blank">HTML</a>{{end}}
+		{{if .Message.Body}}<a href="{{.Base}}/text" target="_blank">Text</a>{{end}}
+		<a href="{{.Base}}/raw" target="_blank">Raw</a>
+	</p>
+	{{if .Message.HTMLBody}}<iframe src="{{.Base}}/html"></iframe>{{end}}
+	{{if .Message.Body}}<h3>Text</h3><pre>{{.Message.Body}}</pre>{{end}}
+	<h3>Raw MIME</h3>
+	<pre>{{.Raw}}</pre>
+</main>
+</body>
+</html>`))
@@ -0,0 +1,287 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	netmail "net/mail"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	_ Sender = &SMTPSender{}
+	_ Sender = &FileSender{}
+	_ Sender = &MemorySender{}
+	_ Sender = &LogSender{}
+	_ Sender = &SendmailSender{}
+	_ Sender = &DKIMSender{}
+)
+
+// Config selects and configures a Sender (see the mail section of config.yml)
+type Config struct {
+	Delivery     string // smtp, file, memory, log, sendmail
+	SMTP         SMTPConfig
+	FileDir      string
+	SendmailPath string
+	SendmailArgs []string
+}
+
+// DefaultDelivery returns the delivery method used when none is configured
+// for the given environment
+func DefaultDelivery(env string) string {
+	switch env {
+	case "development":
+		return "log"
+	case "test":
+		return "memory"
+	default:
+		return "smtp"
+	}
+}
+
+// NewSender creates a Sender based on the configured delivery method
+func NewSender(cfg Config) (Sender, error) {
+	switch strings.ToLower(cfg.Delivery) {
+	case "smtp":
+		if cfg.SMTP.Host == "" {
+			return nil, fmt.Errorf("smtp delivery requires mail.smtp.host")
+		}
+		return NewSMTPSenderWithConfig(cfg.SMTP), nil
+	case "file":
+		return NewFileSender(cfg.FileDir), nil
+	case "memory":
+		return NewMemorySender(), nil
+	case "log":
+		return NewLogSender(), nil
+	case "sendmail":
+		return NewSendmailSender(cfg.SendmailPath, cfg.SendmailArgs...), nil
+	default:
+		return nil, fmt.Errorf("unsupported mail delivery: %s (supported: smtp, file, memory, log, sendmail)", cfg.Delivery)
+	}
+}
+
+// validateMessage checks the fields every sender needs
+func validateMessage(msg *Message) error {
+	if msg.From == "" {
+		return fmt.Errorf("from address is required")
+	}
+	if len(msg.To) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+	return nil
+}
+
+// envelopeAddress extracts the bare address from "Name <user@example.com>"
+// for use in the SMTP envelope
+func envelopeAddress(addr string) string {
+	if parsed, err := netmail.ParseAddress(addr); err == nil {
+		return parsed.Address
+	}
+	return addr
+}
+
+// envelopeAddresses applies envelopeAddress to every address
+func envelopeAddresses(addrs []string) []string {
+	result := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		result = append(result, envelopeAddress(addr))
+	}
+	return result
+}
+
+// FileSender writes each message as an .eml file, useful in development
+// to open emails in a desktop mail client
+type FileSender struct {
+	dir string
+}
+
+// NewFileSender creates a sender that writes to dir (default: tmp/mail)
+func NewFileSender(dir string) *FileSender {
+	if dir == "" {
+		dir = filepath.Join("tmp", "mail")
+	}
+	return &FileSender{dir: dir}
+}
+
+// Dir returns the directory messages are written to
+func (s *FileSender) Dir() string {
+	return s.dir
+}
+
+// Send writes the message to a new .eml file
+func (s *FileSender) Send(msg *Message) error {
+	if err := validateMessage(msg); err != nil {
+		return err
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	b := make([]byte, 4)
+	rand.Read(b)
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000"), hex.EncodeToString(b))
+
+	return os.WriteFile(filepath.Join(s.dir, name), data, 0644)
+}
+
+// MemorySender keeps sent messages in memory so tests can inspect them
+type MemorySender struct {
+	messages []*Message
+	mu       sync.RWMutex
+}
+
+// NewMemorySender creates a new in-memory sender
+func NewMemorySender() *MemorySender {
+	return &MemorySender{
+		messages: make([]*Message, 0),
+	}
+}
+
+// Send records the message
+func (s *MemorySender) Send(msg *Message) error {
+	if err := validateMessage(msg); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Messages returns all recorded messages in the order they were sent
+func (s *MemorySender) Messages() []*Message {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*Message, len(s.messages))
+	copy(result, s.messages)
+	return result
+}
+
+// Last returns the most recently sent message, or nil if none
+func (s *MemorySender) Last() *Message {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.messages) == 0 {
+		return nil
+	}
+	return s.messages[len(s.messages)-1]
+}
+
+// Len returns the number of recorded messages
+func (s *MemorySender) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.messages)
+}
+
+// SentTo returns the messages addressed to addr (To, Cc or Bcc)
+func (s *MemorySender) SentTo(addr string) []*Message {
+	var result []*Message
+	for _, msg := range s.Messages() {
+		for _, recipient := range msg.Recipients() {
+			if strings.EqualFold(recipient, addr) {
+				result = append(result, msg)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Reset clears all recorded messages
+func (s *MemorySender) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = make([]*Message, 0)
+}
+
+// LogSender prints messages to the log instead of sending them
+type LogSender struct {
+	logger *log.Logger
+}
+
+// NewLogSender creates a sender that logs messages
+func NewLogSender() *LogSender {
+	return &LogSender{
+		logger: log.New(log.Writer(), "[Mail] ", log.LstdFlags),
+	}
+}
+
+// Send logs the message headers and text body
+func (s *LogSender) Send(msg *Message) error {
+	if err := validateMessage(msg); err != nil {
+		return err
+	}
+
+	body := msg.Body
+	if body == "" {
+		body = msg.HTMLBody
+	}
+
+	s.logger.Printf("📧 From: %s | To: %s | Subject: %s | Attachments: %d\n%s",
+		msg.From,
+		strings.Join(msg.Recipients(), ", "),
+		msg.Subject,
+		len(msg.Attachments),
+		body,
+	)
+	return nil
+}
+
+// SendmailSender pipes messages to a local sendmail-compatible binary
+type SendmailSender struct {
+	path string
+	args []string
+}
+
+// NewSendmailSender creates a sender using the sendmail binary at path
+// (default: /usr/sbin/sendmail). Extra args are passed before the recipients.
+func NewSendmailSender(path string, args ...string) *SendmailSender {
+	if path == "" {
+		path = "/usr/sbin/sendmail"
+	}
+	return &SendmailSender{path: path, args: args}
+}
+
+// Send runs sendmail with the envelope sender and recipients and writes the
+// message to its stdin
+func (s *SendmailSender) Send(msg *Message) error {
+	if err := validateMessage(msg); err != nil {
+		return err
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	args := append([]string{"-i", "-f", envelopeAddress(msg.From)}, s.args...)
+	args = append(args, "--")
+	args = append(args, envelopeAddresses(msg.Recipients())...)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(s.path, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sendmail failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+package mail
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TLS modes for SMTPConfig.TLS
+const (
+	TLSOpportunistic = "opportunistic" // STARTTLS when the server offers it (default)
+	TLSStartTLS      = "starttls"      // STARTTLS required
+	TLSImplicit      = "tls"           // TLS from the first byte (usually port 465)
+	TLSNone          = "none"          // Plain text connection
+)
+
+// Authentication methods for SMTPConfig.AuthMethod
+const (
+	AuthPlain   = "plain"
+	AuthLogin   = "login"
+	AuthCRAMMD5 = "cram-md5"
+	AuthNone    = "none"
+)
+
+// SMTPConfig holds SMTP configuration
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Auth     smtp.Auth // Overrides AuthMethod when set
+
+	AuthMethod         string        // plain (default), login, cram-md5, none
+	TLS                string        // opportunistic (default), starttls, tls, none
+	TLSConfig          *tls.Config   // Custom TLS settings (ServerName defaults to Host)
+	InsecureSkipVerify bool          // Skip certificate verification (development only)
+	LocalName          string        // Name sent with EHLO (default: localhost)
+	DialTimeout        time.Duration // Default: 10s
+	Timeout            time.Duration // Deadline for each message transaction. Default: 30s
+	KeepAlive          bool          // Reuse one connection across messages for bulk sending
+}
+
+// SMTPSender implements Sender using SMTP
+type SMTPSender struct {
+	config SMTPConfig
+	conn   *smtpConn // Persistent connection when KeepAlive is enabled
+	mu     sync.Mutex
+}
+
+// smtpConn pairs a client with its connection so deadlines can be renewed
+type smtpConn struct {
+	client *smtp.Client
+	conn   net.Conn
+}
+
+// RecipientError reports recipients rejected by the SMTP server. Unless every
+// recipient was rejected the message was still delivered to the others.
+type RecipientError struct {
+	Rejected map[string]error
+}
+
+func (e *RecipientError) Error() string {
+	addrs := make([]string, 0, len(e.Rejected))
+	for addr := range e.Rejected {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	parts := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		parts = append(parts, fmt.Sprintf("%s: %v", addr, e.Rejected[addr]))
+	}
+	return "recipients rejected: " + strings.Join(parts, "; ")
+}
+
+// NewSMTPSender creates a new SMTP sender
+func NewSMTPSender(host string, port int, username, password string) *SMTPSender {
+	return NewSMTPSenderWithConfig(SMTPConfig{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+	})
+}
+
+// NewSMTPSenderWithConfig creates an SMTP sender with TLS, auth and
+// connection settings
+func NewSMTPSenderWithConfig(config SMTPConfig) *SMTPSender {
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	if config.TLS == "" {
+		config.TLS = TLSOpportunistic
+		if config.Port == 465 {
+			config.TLS = TLSImplicit
+		}
+	}
+	if config.AuthMethod == "" {
+		config.AuthMethod = AuthPlain
+	}
+	if config.LocalName == "" {
+		config.LocalName = "localhost"
+	}
+	if config.DialTimeout == 0 {
+		config.DialTimeout = 10 * time.Second
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	return &SMTPSender{config: config}
+}
+
+// Send sends an email message via SMTP
+func (s *SMTPSender) Send(msg *Message) error {
+	if err := validateMessage(msg); err != nil {
+		return err
+	}
+
+	// Build the full MIME message
+	email, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, err := s.connection()
+	if err != nil {
+		return err
+	}
+
+	c.conn.SetDeadline(time.Now().Add(s.config.Timeout))
+	err = s.deliver(c.client, envelopeAddress(msg.From), envelopeAddresses(msg.Recipients()), email)
+
+	var rcptErr *RecipientError
+	switch {
+	case !s.config.KeepAlive:
+		c.client.Quit()
+	case err != nil && !errors.As(err, &rcptErr):
+		// The connection is in an unknown state, dial again next time
+		c.client.Close()
+		s.conn = nil
+	}
+
+	return err
+}
+
+// Close closes the persistent connection, if any
+func (s *SMTPSender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	s.conn.conn.SetDeadline(time.Now().Add(s.config.Timeout))
+	err := s.conn.client.Quit()
+	s.conn = nil
+	return err
+}
+
+// connection returns the persistent connection when it's still usable or
+// dials a new one
+func (s *SMTPSender) connection() (*smtpConn, error) {
+	if s.conn != nil {
+		s.conn.conn.SetDeadline(time.Now().Add(s.config.Timeout))
+		if err := s.conn.client.Reset(); err == nil {
+			return s.conn, nil
+		}
+		s.conn.client.Close()
+		s.conn = nil
+	}
+
+	c, err := s.dial()
+	if err != nil {
+		return nil, err
+	}
+	if s.config.KeepAlive {
+		s.conn = c
+	}
+	return c, nil
+}
+
+// dial connects, negotiates TLS and authenticates
+func (s *SMTPSender) dial() (*smtpConn, error) {
+	addr := net.JoinHostPort(s.config.Host, fmt.Sprintf("%d", s.config.Port))
+	dialer := &net.Dialer{Timeout: s.config.DialTimeout}
+
+	var conn net.Conn
+	var err error
+	if s.config.TLS == TLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, s.tlsConfig())
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+
+	// Bound the handshake by the transaction timeout
+	conn.SetDeadline(time.Now().Add(s.config.Timeout))
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := s.handshake(client); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &smtpConn{client: client, conn: conn}, nil
+}
+
+func (s *SMTPSender) handshake(client *smtp.Client) error {
+	if err := client.Hello(s.config.LocalName); err != nil {
+		return err
+	}
+
+	if s.config.TLS == TLSOpportunistic || s.config.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(s.tlsConfig()); err != nil {
+				return fmt.Errorf("STARTTLS failed: %w", err)
+			}
+		} else if s.config.TLS == TLSStartTLS {
+			return fmt.Errorf("server %s does not support STARTTLS", s.config.Host)
+		}
+	}
+
+	auth, err := s.auth()
+	if err != nil || auth == nil {
+		return err
+	}
+	if ok, _ := client.Extension("AUTH"); !ok {
+		return fmt.Errorf("server %s does not support authentication", s.config.Host)
+	}
+	return client.Auth(auth)
+}
+
+// deliver runs a single mail transaction, collecting rejected recipients
+func (s *SMTPSender) deliver(client *smtp.Client, from string, recipients []string, email []byte) error {
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+
+	rejected := make(map[string]error)
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(rcpt); err != nil {
+			// Only SMTP replies are per-recipient, anything else is a connection failure
+			var reply *textproto.Error
+			if !errors.As(err, &reply) {
+				return err
+			}
+			rejected[rcpt] = err
+		}
+	}
+
+	if len(rejected) == len(recipients) {
+		client.Reset()
+		return &RecipientError{Rejected: rejected}
+	}
+
+	wc, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := wc.Write(email); err != nil {
+		wc.Close()
+		return err
+	}
+	if err := wc.Close(); err != nil {
+		return err
+	}
+
+	if len(rejected) > 0 {
+		return &RecipientError{Rejected: rejected}
+	}
+	return nil
+}
+
+func (s *SMTPSender) tlsConfig() *tls.Config {
+	if s.config.TLSConfig != nil {
+		cfg := s.config.TLSConfig.Clone()
+		if cfg.ServerName == "" {
+			cfg.ServerName = s.config.Host
+		}
+		return cfg
+	}
+	return &tls.Config{
+		ServerName:         s.config.Host,
+		InsecureSkipVerify: s.config.InsecureSkipVerify,
+	}
+}
+
+func (s *SMTPSender) auth() (smtp.Auth, error) {
+	if s.config.Auth != nil {
+		return s.config.Auth, nil
+	}
+	if s.config.Username == "" {
+		return nil, nil
+	}
+
+	switch strings.ToLower(s.config.AuthMethod) {
+	case AuthPlain:
+		return smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host), nil
+	case AuthLogin:
+		return &loginAuth{username: s.config.Username, password: s.config.Password, host: s.config.Host}, nil
+	case AuthCRAMMD5:
+		return smtp.CRAMMD5Auth(s.config.Username, s.config.Password), nil
+	case AuthNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported smtp auth method: %s (supported: plain, login, cram-md5, none)", s.config.AuthMethod)
+	}
+}
+
+// loginAuth implements the LOGIN mechanism still required by some servers
+// (e.g. Office 365)
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// Like PlainAuth, refuse to send credentials over an unencrypted
+	// connection except to localhost
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN challenge: %s", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
@@ -0,0 +1,176 @@
+package mail
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	rtesting "github.com/Palaciodiego008/rebololang/pkg/rebolo/testing"
+)
+
+func testMessage(to ...string) *Message {
+	msg := NewMessage()
+	msg.From = "app@example.com"
+	for _, addr := range to {
+		msg.AddTo(addr)
+	}
+	msg.Subject = "Hello"
+	msg.Body = "Hi there"
+	return msg
+}
+
+func TestSMTPSender(t *testing.T) {
+	tests := []struct {
+		name        string
+		implicitTLS bool // Server created with NewTLSSMTPServer
+		startTLS    bool // Server advertises STARTTLS
+		credentials bool // Server requires user/secret
+		config      SMTPConfig
+		wantTLS     bool
+		wantAuth    string
+		wantErr     string
+	}{
+		{name: "plain text", config: SMTPConfig{TLS: TLSNone}},
+		{name: "opportunistic without STARTTLS", config: SMTPConfig{}},
+		{name: "opportunistic STARTTLS", startTLS: true, config: SMTPConfig{}, wantTLS: true},
+		{name: "required STARTTLS", startTLS: true, config: SMTPConfig{TLS: TLSStartTLS}, wantTLS: true},
+		{name: "required STARTTLS missing", config: SMTPConfig{TLS: TLSStartTLS}, wantErr: "does not support STARTTLS"},
+		{name: "implicit TLS", implicitTLS: true, config: SMTPConfig{TLS: TLSImplicit}, wantTLS: true},
+		{
+			name: "AUTH PLAIN", startTLS: true, credentials: true,
+			config:  SMTPConfig{Username: "user", Password: "secret"},
+			wantTLS: true, wantAuth: "user",
+		},
+		{
+			name: "AUTH LOGIN", startTLS: true, credentials: true,
+			config:  SMTPConfig{Username: "user", Password: "secret", AuthMethod: AuthLogin},
+			wantTLS: true, wantAuth: "user",
+		},
+		{
+			name: "AUTH CRAM-MD5", credentials: true,
+			config:   SMTPConfig{Username: "user", Password: "secret", AuthMethod: AuthCRAMMD5, TLS: TLSNone},
+			wantAuth: "user",
+		},
+		{
+			name: "wrong password", credentials: true,
+			config:  SMTPConfig{Username: "user", Password: "wrong", AuthMethod: AuthCRAMMD5, TLS: TLSNone},
+			wantErr: "535",
+		},
+		{
+			name: "unknown auth method", credentials: true,
+			config:  SMTPConfig{Username: "user", Password: "secret", AuthMethod: "xoauth2", TLS: TLSNone},
+			wantErr: "unsupported smtp auth method",
+		},
+		{name: "authentication required", credentials: true, config: SMTPConfig{TLS: TLSNone}, wantErr: "530"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := rtesting.NewSMTPServer()
+			if tt.implicitTLS {
+				server.Close()
+				server = rtesting.NewTLSSMTPServer()
+			}
+			defer server.Close()
+			if tt.startTLS {
+				server.EnableStartTLS()
+			}
+			if tt.credentials {
+				server.SetCredentials("user", "secret")
+			}
+
+			config := tt.config
+			config.Host, config.Port = server.Host(), server.Port()
+			config.TLSConfig = server.TLSConfig()
+			err := NewSMTPSenderWithConfig(config).Send(testMessage("to@example.com"))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Send() error = %v, want %q", err, tt.wantErr)
+				}
+				if n := len(server.Messages()); n != 0 {
+					t.Errorf("server received %d messages, want none", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			messages := server.Messages()
+			if len(messages) != 1 {
+				t.Fatalf("server received %d messages, want 1", len(messages))
+			}
+			got := messages[0]
+			if got.From != "app@example.com" || len(got.To) != 1 || got.To[0] != "to@example.com" {
+				t.Errorf("envelope = %s -> %v, want app@example.com -> [to@example.com]", got.From, got.To)
+			}
+			if !strings.Contains(string(got.Data), "Subject: Hello") {
+				t.Errorf("message has no subject:\n%s", got.Data)
+			}
+			if got.TLS != tt.wantTLS {
+				t.Errorf("sent over TLS = %v, want %v", got.TLS, tt.wantTLS)
+			}
+			if got.Auth != tt.wantAuth {
+				t.Errorf("authenticated as %q, want %q", got.Auth, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestSMTPSenderUntrustedCertificate(t *testing.T) {
+	server := rtesting.NewSMTPServer()
+	defer server.Close()
+	server.EnableStartTLS()
+
+	sender := NewSMTPSenderWithConfig(SMTPConfig{Host: server.Host(), Port: server.Port()})
+	if err := sender.Send(testMessage("to@example.com")); err == nil || !strings.Contains(err.Error(), "STARTTLS failed") {
+		t.Fatalf("Send() error = %v, want the self-signed certificate refused", err)
+	}
+}
+
+func TestSMTPSenderKeepAlive(t *testing.T) {
+	tests := []struct {
+		name      string
+		keepAlive bool
+		wantConns int
+	}{
+		{"new connection per message", false, 3},
+		{"reused connection", true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := rtesting.NewSMTPServer()
+			defer server.Close()
+			server.SetCredentials("user", "secret")
+			server.Reject("bounce@example.com")
+
+			sender := NewSMTPSenderWithConfig(SMTPConfig{
+				Host: server.Host(), Port: server.Port(), TLS: TLSNone,
+				Username: "user", Password: "secret", KeepAlive: tt.keepAlive,
+			})
+			defer sender.Close()
+
+			if err := sender.Send(testMessage("a@example.com")); err != nil {
+				t.Fatal(err)
+			}
+			// A rejected recipient doesn't break the connection
+			err := sender.Send(testMessage("b@example.com", "bounce@example.com"))
+			var rcptErr *RecipientError
+			if !errors.As(err, &rcptErr) || len(rcptErr.Rejected) != 1 || rcptErr.Rejected["bounce@example.com"] == nil {
+				t.Fatalf("Send() error = %v, want bounce@example.com rejected", err)
+			}
+			if err := sender.Send(testMessage("c@example.com")); err != nil {
+				t.Fatal(err)
+			}
+
+			if n := len(server.Messages()); n != 3 {
+				t.Errorf("server received %d messages, want 3", n)
+			}
+			if n := server.Connections(); n != tt.wantConns {
+				t.Errorf("opened %d connections, want %d", n, tt.wantConns)
+			}
+		})
+	}
+}
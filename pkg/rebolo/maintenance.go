@@ -0,0 +1,36 @@
+package rebolo
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/maintenance"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/middleware"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/ports"
+)
+
+// newMaintenance creates the maintenance mode middleware configured in the
+// maintenance section of config.yml. Refused requests go to onMaintenance.
+func newMaintenance(configData ports.ConfigData, database adapters.DatabaseAdapter, onMaintenance func(w http.ResponseWriter, r *http.Request, err error)) middleware.MiddlewareFunc {
+	cfg := configData.Maintenance
+
+	var db *sql.DB
+	if cfg.Store == "sql" && database != nil {
+		db, _ = database.DB().(*sql.DB)
+	}
+	store, err := maintenance.OpenStore(cfg.Store, cfg.File, db)
+	if err != nil {
+		log.Printf("⚠️  Maintenance store unavailable, using the flag file: %v", err)
+		store = maintenance.NewFileStore(cfg.File)
+	}
+
+	return middleware.Maintenance(middleware.MaintenanceConfig{
+		Store:         store,
+		AllowIPs:      cfg.AllowIPs,
+		Skip:          cfg.Skip,
+		CheckInterval: cfg.CheckInterval,
+		OnMaintenance: onMaintenance,
+	})
+}
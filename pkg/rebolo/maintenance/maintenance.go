@@ -0,0 +1,129 @@
+package maintenance
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrMaintenance is reported for requests refused during maintenance
+var ErrMaintenance = errors.New("the application is under maintenance")
+
+// CookieName is the cookie letting staff through during maintenance
+const CookieName = "rebolo_maintenance"
+
+// State describes the current maintenance window
+type State struct {
+	Message    string        `json:"message,omitempty"`     // Shown on the 503 page
+	RetryAfter time.Duration `json:"retry_after,omitempty"` // Sent in Retry-After (default: 5m)
+	AllowIPs   []string      `json:"allow_ips,omitempty"`   // IPs or CIDR ranges let through
+	Secret     string        `json:"secret,omitempty"`      // Value of the bypass cookie
+	Since      time.Time     `json:"since"`
+}
+
+// Store keeps the maintenance state, so every instance of the app sees it
+// without a redeploy
+type Store interface {
+	// Load returns the state, or nil when the app isn't under maintenance
+	Load(ctx context.Context) (*State, error)
+	Save(ctx context.Context, state *State) error
+	// Clear ends the maintenance
+	Clear(ctx context.Context) error
+}
+
+// NewSecret returns a random bypass secret
+func NewSecret() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AllowsIP reports whether ip is in AllowIPs or in extra
+func (s *State) AllowsIP(ip string, extra ...string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, allowed := range append(append([]string{}, s.AllowIPs...), extra...) {
+		if strings.Contains(allowed, "/") {
+			if _, network, err := net.ParseCIDR(allowed); err == nil && network.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(allowed); allowedIP != nil && allowedIP.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsSecret reports whether secret is the bypass secret
+func (s *State) AllowsSecret(secret string) bool {
+	return s.Secret != "" && subtle.ConstantTimeCompare([]byte(s.Secret), []byte(secret)) == 1
+}
+
+// Checker caches the state of a store for a short interval, so requests
+// don't hit the store each time
+type Checker struct {
+	store    Store
+	interval time.Duration
+
+	mu      sync.Mutex
+	state   *State
+	checked time.Time
+}
+
+// NewChecker creates a checker reloading the state every interval
+// (default: 2s)
+func NewChecker(store Store, interval time.Duration) *Checker {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	return &Checker{store: store, interval: interval}
+}
+
+// State returns the current state, or nil when the app isn't under
+// maintenance. Store errors are logged and the last state is kept.
+func (c *Checker) State(ctx context.Context) *State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checked) < c.interval {
+		return c.state
+	}
+	c.checked = time.Now()
+
+	state, err := c.store.Load(ctx)
+	if err != nil {
+		log.Printf("⚠️  Failed to load maintenance state: %v", err)
+		return c.state
+	}
+	c.state = state
+	return state
+}
+
+// Error is the error of requests refused during maintenance, reading as
+// the message of the state
+type Error struct {
+	State *State
+}
+
+func (e *Error) Error() string {
+	if e.State != nil && e.State.Message != "" {
+		return e.State.Message
+	}
+	return ErrMaintenance.Error()
+}
+
+// Is makes errors.Is(err, ErrMaintenance) true
+func (e *Error) Is(target error) bool {
+	return target == ErrMaintenance
+}
@@ -0,0 +1,172 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+)
+
+// DefaultFile is the flag file of NewFileStore("")
+var DefaultFile = filepath.Join("tmp", "maintenance.json")
+
+// FileStore keeps the state in a flag file, present during maintenance.
+// Instances on other servers need a shared disk, or SQLStore.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a store using the flag file at path (default:
+// tmp/maintenance.json)
+func NewFileStore(path string) *FileStore {
+	if path == "" {
+		path = DefaultFile
+	}
+	return &FileStore{path: path}
+}
+
+func (s *FileStore) Load(ctx context.Context) (*State, error) {
+	content, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, state); err != nil {
+			return nil, fmt.Errorf("invalid maintenance file %s: %w", s.path, err)
+		}
+	}
+	return state, nil
+}
+
+func (s *FileStore) Save(ctx context.Context, state *State) error {
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create maintenance directory: %w", err)
+	}
+
+	// Write to a temporary file first so readers never see partial data
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *FileStore) Clear(ctx context.Context) error {
+	err := os.Remove(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// SQLStore keeps the state in a one row table, shared by every instance:
+//
+//	CREATE TABLE maintenance (
+//		id INTEGER PRIMARY KEY,
+//		data TEXT NOT NULL
+//	)
+type SQLStore struct {
+	db    *sql.DB
+	table string
+}
+
+var validTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewSQLStore creates a store using the "maintenance" table, creating it
+// if needed
+// Usage: store, err := maintenance.NewSQLStore(app.DB())
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	return NewSQLStoreWithTable(db, "maintenance")
+}
+
+// NewSQLStoreWithTable creates a SQL store using table
+func NewSQLStoreWithTable(db *sql.DB, table string) (*SQLStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("sql maintenance store requires a database connection")
+	}
+	if !validTableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid maintenance table name: %s", table)
+	}
+
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id INTEGER PRIMARY KEY,
+	data TEXT NOT NULL
+)`, table)
+	if _, err := db.Exec(query); err != nil {
+		return nil, fmt.Errorf("failed to create maintenance table: %w", err)
+	}
+	return &SQLStore{db: db, table: table}, nil
+}
+
+func (s *SQLStore) Load(ctx context.Context) (*State, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT data FROM %s WHERE id = 1", s.table)).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{}
+	if err := json.Unmarshal([]byte(data), state); err != nil {
+		return nil, fmt.Errorf("invalid maintenance state: %w", err)
+	}
+	return state, nil
+}
+
+// Save replaces the row inside a transaction, which works the same on
+// PostgreSQL, MySQL and SQLite
+func (s *SQLStore) Save(ctx context.Context, state *State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = 1", s.table)); err != nil {
+		return err
+	}
+	insert := adapters.Rebind(s.db, fmt.Sprintf("INSERT INTO %s (id, data) VALUES (1, ?)", s.table))
+	if _, err := tx.ExecContext(ctx, insert, string(data)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) Clear(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = 1", s.table))
+	return err
+}
+
+// OpenStore returns the store named in the maintenance section of
+// config.yml: file (default) or sql, using db
+func OpenStore(store, file string, db *sql.DB) (Store, error) {
+	switch store {
+	case "", "file":
+		return NewFileStore(file), nil
+	case "sql":
+		return NewSQLStore(db)
+	default:
+		return nil, fmt.Errorf("unknown maintenance store: %s (supported: file, sql)", store)
+	}
+}
@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/logging"
+)
+
+// Access log fields
+const (
+	FieldMethod    = "method"
+	FieldPath      = "path"
+	FieldQuery     = "query"
+	FieldStatus    = "status"
+	FieldSize      = "size"
+	FieldDuration  = "duration"
+	FieldIP        = "ip"
+	FieldUserAgent = "user_agent"
+	FieldReferer   = "referer"
+	FieldRequestID = "request_id"
+	FieldHost      = "host"
+	FieldProto     = "proto"
+)
+
+// DefaultAccessLogFields are logged when AccessLogConfig.Fields is empty
+var DefaultAccessLogFields = []string{
+	FieldMethod, FieldPath, FieldStatus, FieldSize, FieldDuration, FieldIP, FieldUserAgent, FieldRequestID,
+}
+
+// AccessLogConfig configures AccessLog
+type AccessLogConfig struct {
+	// Logger receives the entries (default: logging.Default())
+	Logger *slog.Logger
+
+	// Fields logged per request (default: DefaultAccessLogFields)
+	Fields []string
+
+	// Skip lists paths not logged, with the Skip patterns of Use (default:
+	// the hot reload polling endpoint)
+	Skip []string
+
+	// SampleRate is the fraction of successful requests logged, between 0
+	// and 1 (default: 1). Client and server errors are always logged.
+	SampleRate float64
+}
+
+// AccessLog logs a structured entry per request: Info for successful
+// requests, Warn for 4xx and Error for 5xx responses.
+// Usage: app.AddMiddleware(middleware.AccessLog(middleware.AccessLogConfig{SampleRate: 0.1}))
+func AccessLog(configs ...AccessLogConfig) MiddlewareFunc {
+	var config AccessLogConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if len(config.Fields) == 0 {
+		config.Fields = DefaultAccessLogFields
+	}
+	if config.Skip == nil {
+		config.Skip = []string{"/__rebolo__/changes"}
+	}
+	if config.SampleRate <= 0 || config.SampleRate > 1 {
+		config.SampleRate = 1
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, pattern := range config.Skip {
+				if matchPath(r.URL.Path, pattern) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r)
+			duration := time.Since(start)
+
+			if sw.status < 400 && config.SampleRate < 1 && rand.Float64() >= config.SampleRate {
+				return
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case sw.status >= 500:
+				level = slog.LevelError
+			case sw.status >= 400:
+				level = slog.LevelWarn
+			}
+
+			logger := config.Logger
+			if logger == nil {
+				logger = logging.Default()
+			}
+			if !logger.Enabled(r.Context(), level) {
+				return
+			}
+			logger.LogAttrs(r.Context(), level, "request", accessLogAttrs(config.Fields, r, sw, duration)...)
+		})
+	}
+}
+
+func accessLogAttrs(fields []string, r *http.Request, sw *statusWriter, duration time.Duration) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, field := range fields {
+		switch field {
+		case FieldMethod:
+			attrs = append(attrs, slog.String(field, r.Method))
+		case FieldPath:
+			attrs = append(attrs, slog.String(field, r.URL.Path))
+		case FieldQuery:
+			attrs = append(attrs, slog.String(field, r.URL.RawQuery))
+		case FieldStatus:
+			attrs = append(attrs, slog.Int(field, sw.status))
+		case FieldSize:
+			attrs = append(attrs, slog.Int(field, sw.size))
+		case FieldDuration:
+			attrs = append(attrs, slog.Duration(field, duration))
+		case FieldIP:
+			ip, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				ip = r.RemoteAddr
+			}
+			attrs = append(attrs, slog.String(field, ip))
+		case FieldUserAgent:
+			attrs = append(attrs, slog.String(field, r.UserAgent()))
+		case FieldReferer:
+			attrs = append(attrs, slog.String(field, r.Referer()))
+		case FieldRequestID:
+			if id := logging.RequestID(r.Context()); id != "" {
+				attrs = append(attrs, slog.String(field, id))
+			}
+		case FieldHost:
+			attrs = append(attrs, slog.String(field, r.Host))
+		case FieldProto:
+			attrs = append(attrs, slog.String(field, r.Proto))
+		}
+	}
+	return attrs
+}
+
+// statusWriter captures the status code and size of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if !sw.wroteHeader && code >= 200 {
+		sw.status = code
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+// Write counts the bytes sent to the client (compressed, with Compress)
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	sw.wroteHeader = true
+	size, err := sw.ResponseWriter.Write(b)
+	sw.size += size
+	return size, err
+}
+
+// Flush supports streaming responses
+func (sw *statusWriter) Flush() {
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack supports websockets, logged as 101
+func (sw *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := sw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("access log: response can't be hijacked")
+	}
+	sw.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
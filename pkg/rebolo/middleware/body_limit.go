@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// DefaultMaxBodySize is the request body limit of MaxBodySize(0)
+const DefaultMaxBodySize int64 = 10 << 20
+
+// MaxBodySize limits request bodies to limit bytes. Reading past it fails
+// with *http.MaxBytesError (413 with errors.StatusCode). It must run before
+// anything reads the body, MethodOverride included.
+// Usage: app.AddMiddleware(middleware.MaxBodySize(1 << 20))
+func MaxBodySize(limit int64) MiddlewareFunc {
+	return MaxBodySizeFunc(func(*http.Request) int64 { return limit })
+}
+
+// MaxBodySizeFunc is MaxBodySize with a limit per request, e.g. per route.
+// Limits <= 0 use DefaultMaxBodySize.
+func MaxBodySizeFunc(limitFor func(r *http.Request) int64) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limit := limitFor(r)
+			if limit <= 0 {
+				limit = DefaultMaxBodySize
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressConfig configures Compress
+type CompressConfig struct {
+	Level   int // gzip/deflate level (default: flate.DefaultCompression)
+	MinSize int // Smaller responses are sent as is (default: 1024 bytes)
+
+	// SkipTypes are content type prefixes never compressed, added to the
+	// already compressed formats (images, video, audio, archives, fonts)
+	SkipTypes []string
+}
+
+// incompressibleTypes are content types that are already compressed or
+// must be streamed as is
+var incompressibleTypes = []string{
+	"image/", "video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip", "application/x-bzip2",
+	"application/x-7z-compressed", "application/x-rar-compressed", "application/x-xz",
+	"application/zstd", "application/pdf", "application/octet-stream", "application/wasm",
+	"text/event-stream",
+}
+
+// Compress compresses responses with gzip or deflate, as negotiated with
+// the Accept-Encoding header. Small bodies, already compressed content
+// types and responses with a Content-Encoding are sent as is.
+// Usage: app.Use(middleware.Compress())
+func Compress(configs ...CompressConfig) MiddlewareFunc {
+	var config CompressConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.Level == 0 {
+		config.Level = flate.DefaultCompression
+	}
+	if config.MinSize <= 0 {
+		config.MinSize = 1024
+	}
+	skipTypes := append(append([]string{}, incompressibleTypes...), config.SkipTypes...)
+	pools := newCompressorPools(config.Level)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				encoding:       negotiateEncoding(r.Header.Get("Accept-Encoding")),
+				minSize:        config.MinSize,
+				skipTypes:      skipTypes,
+				pools:          pools,
+				status:         http.StatusOK,
+			}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// compressorPools reuses gzip and deflate (zlib, as HTTP defines it) writers
+type compressorPools struct {
+	gzip    sync.Pool
+	deflate sync.Pool
+}
+
+func newCompressorPools(level int) *compressorPools {
+	p := &compressorPools{}
+	p.gzip.New = func() interface{} {
+		w, err := gzip.NewWriterLevel(io.Discard, level)
+		if err != nil {
+			w = gzip.NewWriter(io.Discard)
+		}
+		return w
+	}
+	p.deflate.New = func() interface{} {
+		w, err := zlib.NewWriterLevel(io.Discard, level)
+		if err != nil {
+			w = zlib.NewWriter(io.Discard)
+		}
+		return w
+	}
+	return p
+}
+
+// compressor is implemented by *gzip.Writer and *zlib.Writer
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+func (p *compressorPools) get(encoding string, w io.Writer) compressor {
+	var c compressor
+	if encoding == "gzip" {
+		c = p.gzip.Get().(*gzip.Writer)
+	} else {
+		c = p.deflate.Get().(*zlib.Writer)
+	}
+	c.Reset(w)
+	return c
+}
+
+func (p *compressorPools) put(encoding string, c compressor) {
+	if encoding == "gzip" {
+		p.gzip.Put(c)
+	} else {
+		p.deflate.Put(c)
+	}
+}
+
+// negotiateEncoding picks gzip or deflate from Accept-Encoding, or ""
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, q := parseEncoding(part)
+		if name == "*" {
+			name = "gzip"
+		}
+		if name != "gzip" && name != "deflate" {
+			continue
+		}
+		// Prefer gzip on ties, it's the better supported one
+		if q > bestQ || (q == bestQ && q > 0 && name == "gzip") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+func parseEncoding(part string) (string, float64) {
+	fields := strings.Split(part, ";")
+	name := strings.ToLower(strings.TrimSpace(fields[0]))
+	q := 1.0
+	for _, param := range fields[1:] {
+		param = strings.TrimSpace(param)
+		if strings.HasPrefix(param, "q=") {
+			if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+				q = v
+			}
+		}
+	}
+	return name, q
+}
+
+// compressWriter buffers the start of the body until it knows whether
+// compressing is worth it
+type compressWriter struct {
+	http.ResponseWriter
+	encoding  string
+	minSize   int
+	skipTypes []string
+	pools     *compressorPools
+
+	status     int
+	buf        []byte
+	decided    bool // Headers were sent, compressing or not
+	compressor compressor
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided {
+		return
+	}
+	// Informational responses go out right away
+	if code >= 100 && code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.status = code
+	// Bodiless responses
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		cw.start(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		if cw.encoding == "" || !cw.eligible() {
+			cw.start(false)
+		} else {
+			cw.buf = append(cw.buf, b...)
+			if len(cw.buf) < cw.minSize {
+				return len(b), nil
+			}
+			cw.start(true)
+			return len(b), cw.writeBuffered()
+		}
+	}
+
+	if cw.compressor != nil {
+		return cw.compressor.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// eligible reports whether the response may be compressed, judging by
+// its headers
+func (cw *compressWriter) eligible() bool {
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, prefix := range cw.skipTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// start sends the headers, set up for compression if compress
+func (cw *compressWriter) start(compress bool) {
+	cw.decided = true
+	header := cw.Header()
+
+	if cw.eligible() && cw.status != http.StatusNoContent && cw.status != http.StatusNotModified {
+		addVary(header, "Accept-Encoding")
+	}
+
+	if compress {
+		// Sniff before the body is compressed, net/http would sniff the gzip bytes
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", http.DetectContentType(cw.buf))
+		}
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+		// The compressed body is a different representation
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		cw.compressor = cw.pools.get(cw.encoding, cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+func (cw *compressWriter) writeBuffered() error {
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.compressor != nil {
+		_, err = cw.compressor.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
+
+// Flush sends what was written so far, compressed when eligible, for
+// streaming responses
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.start(cw.encoding != "" && cw.eligible())
+		cw.writeBuffered()
+	}
+	if cw.compressor != nil {
+		cw.compressor.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close finishes the response once the handler returned
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		// Under MinSize (or empty), send it as is
+		cw.start(false)
+		if len(cw.buf) > 0 {
+			cw.Header().Set("Content-Length", strconv.Itoa(len(cw.buf)))
+		}
+		return cw.writeBuffered()
+	}
+
+	if cw.compressor == nil {
+		return nil
+	}
+	err := cw.compressor.Close()
+	cw.compressor.Reset(io.Discard)
+	cw.pools.put(cw.encoding, cw.compressor)
+	cw.compressor = nil
+	return err
+}
+
+// Hijack supports websockets through the middleware
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok || cw.decided {
+		return nil, nil, fmt.Errorf("compress: response can't be hijacked")
+	}
+	cw.decided = true
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// addVary adds value to the Vary header unless present
+func addVary(header http.Header, value string) {
+	for _, v := range header.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "*" || strings.EqualFold(field, value) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", value)
+}
@@ -0,0 +1,244 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures cross-origin requests
+type CORSConfig struct {
+	// AllowOrigins lists the allowed origins: exact ones
+	// ("https://app.example.com"), patterns ("https://*.example.com") or
+	// "*" for any origin
+	AllowOrigins []string
+
+	// AllowOriginFunc decides for origins not in AllowOrigins
+	AllowOriginFunc func(origin string) bool
+
+	// AllowMethods default to GET, HEAD, POST, PUT, PATCH and DELETE
+	AllowMethods []string
+
+	// AllowHeaders are the request headers allowed in preflights (default:
+	// Content-Type, Authorization, X-Requested-With and X-CSRF-Token). "*"
+	// allows whatever the preflight asks for.
+	AllowHeaders []string
+
+	// ExposeHeaders are the response headers scripts may read
+	ExposeHeaders []string
+
+	// AllowCredentials lets browsers send cookies and Authorization headers.
+	// The origin is then echoed back, even with AllowOrigins "*".
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache preflight results (not sent
+	// when zero)
+	MaxAge time.Duration
+}
+
+// CORSPolicy is a compiled CORSConfig
+type CORSPolicy struct {
+	config       CORSConfig
+	anyOrigin    bool
+	origins      map[string]bool
+	patterns     []string
+	methods      map[string]bool
+	allowMethods string
+	anyHeader    bool
+	headers      map[string]bool
+	allowHeaders string
+	expose       string
+	maxAge       string
+}
+
+// NewCORSPolicy compiles config
+func NewCORSPolicy(config CORSConfig) *CORSPolicy {
+	if len(config.AllowMethods) == 0 {
+		config.AllowMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+	}
+	if len(config.AllowHeaders) == 0 {
+		config.AllowHeaders = []string{"Content-Type", "Authorization", "X-Requested-With", "X-CSRF-Token"}
+	}
+
+	p := &CORSPolicy{
+		config:  config,
+		origins: make(map[string]bool),
+		methods: make(map[string]bool),
+		headers: make(map[string]bool),
+		expose:  strings.Join(config.ExposeHeaders, ", "),
+	}
+
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "*"):
+			p.patterns = append(p.patterns, origin)
+		default:
+			p.origins[origin] = true
+		}
+	}
+
+	methods := make([]string, 0, len(config.AllowMethods))
+	for _, method := range config.AllowMethods {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		p.methods[method] = true
+		methods = append(methods, method)
+	}
+	p.allowMethods = strings.Join(methods, ", ")
+
+	headers := make([]string, 0, len(config.AllowHeaders))
+	for _, header := range config.AllowHeaders {
+		header = strings.TrimSpace(header)
+		if header == "*" {
+			p.anyHeader = true
+			continue
+		}
+		p.headers[http.CanonicalHeaderKey(header)] = true
+		headers = append(headers, header)
+	}
+	p.allowHeaders = strings.Join(headers, ", ")
+
+	if config.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(config.MaxAge.Seconds()))
+	}
+	return p
+}
+
+// AllowsOrigin reports whether requests from origin are allowed
+func (p *CORSPolicy) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	lower := strings.ToLower(origin)
+	if p.origins[lower] {
+		return true
+	}
+	// "null" (sandboxed iframes, file://) only when listed
+	if p.anyOrigin && lower != "null" {
+		return true
+	}
+	for _, pattern := range p.patterns {
+		// "*" doesn't match "/", so it only spans host labels
+		if ok, _ := path.Match(pattern, lower); ok {
+			return true
+		}
+	}
+	return p.config.AllowOriginFunc != nil && p.config.AllowOriginFunc(origin)
+}
+
+// variesByOrigin reports whether Access-Control-Allow-Origin depends on the
+// request Origin
+func (p *CORSPolicy) variesByOrigin() bool {
+	return !p.anyOrigin || p.config.AllowCredentials || len(p.origins) > 0 ||
+		len(p.patterns) > 0 || p.config.AllowOriginFunc != nil
+}
+
+// setAllowOrigin sets Access-Control-Allow-Origin (and credentials)
+func (p *CORSPolicy) setAllowOrigin(header http.Header, origin string) {
+	if p.variesByOrigin() {
+		header.Set("Access-Control-Allow-Origin", origin)
+	} else {
+		header.Set("Access-Control-Allow-Origin", "*")
+	}
+	if p.config.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// SetHeaders adds the CORS headers of an actual (non preflight) request
+func (p *CORSPolicy) SetHeaders(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	if p.variesByOrigin() {
+		addVary(header, "Origin")
+	}
+
+	origin := r.Header.Get("Origin")
+	if !p.AllowsOrigin(origin) {
+		return
+	}
+	p.setAllowOrigin(header, origin)
+	if p.expose != "" {
+		header.Set("Access-Control-Expose-Headers", p.expose)
+	}
+}
+
+// Preflight answers a preflight request with 204. The allow headers are
+// only sent when the origin, method and headers asked for are allowed.
+func (p *CORSPolicy) Preflight(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	addVary(header, "Origin")
+	addVary(header, "Access-Control-Request-Method")
+	addVary(header, "Access-Control-Request-Headers")
+
+	origin := r.Header.Get("Origin")
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	requested := requestedHeaders(r)
+
+	if p.AllowsOrigin(origin) && p.methods[method] && p.allowsHeaders(requested) {
+		p.setAllowOrigin(header, origin)
+		header.Set("Access-Control-Allow-Methods", p.allowMethods)
+		if p.anyHeader {
+			if len(requested) > 0 {
+				header.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+			}
+		} else if p.allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", p.allowHeaders)
+		}
+		if p.maxAge != "" {
+			header.Set("Access-Control-Max-Age", p.maxAge)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *CORSPolicy) allowsHeaders(requested []string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for _, header := range requested {
+		if !p.headers[header] {
+			return false
+		}
+	}
+	return true
+}
+
+// requestedHeaders parses Access-Control-Request-Headers
+func requestedHeaders(r *http.Request) []string {
+	var headers []string
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, header := range strings.Split(value, ",") {
+			if header = strings.TrimSpace(header); header != "" {
+				headers = append(headers, http.CanonicalHeaderKey(header))
+			}
+		}
+	}
+	return headers
+}
+
+// IsPreflight reports whether r is a CORS preflight request
+func IsPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// CORS allows cross-origin requests as configured. Preflights are answered
+// for any path, app.EnableCORS only answers them for routes that exist.
+// Usage: app.Use(middleware.CORS(middleware.CORSConfig{AllowOrigins: []string{"https://app.example.com"}}))
+func CORS(config CORSConfig) MiddlewareFunc {
+	policy := NewCORSPolicy(config)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if IsPreflight(r) {
+				policy.Preflight(w, r)
+				return
+			}
+			policy.SetHeaders(w, r)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
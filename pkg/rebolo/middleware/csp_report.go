@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+)
+
+// maxCSPReportSize caps the body of violation reports
+const maxCSPReportSize = 64 << 10
+
+// CSPReport is a Content Security Policy violation sent by a browser
+type CSPReport struct {
+	DocumentURL        string
+	Referrer           string
+	BlockedURL         string
+	EffectiveDirective string
+	OriginalPolicy     string
+	Disposition        string // enforce or report
+	SourceFile         string
+	Sample             string
+	LineNumber         int
+	ColumnNumber       int
+	StatusCode         int
+}
+
+// legacyCSPReport is the report-uri format (application/csp-report)
+type legacyCSPReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		Referrer           string `json:"referrer"`
+		BlockedURI         string `json:"blocked-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		OriginalPolicy     string `json:"original-policy"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"source-file"`
+		ScriptSample       string `json:"script-sample"`
+		LineNumber         int    `json:"line-number"`
+		ColumnNumber       int    `json:"column-number"`
+		StatusCode         int    `json:"status-code"`
+	} `json:"csp-report"`
+}
+
+// reportingAPIReport is the Reporting API format (application/reports+json)
+type reportingAPIReport struct {
+	Type string `json:"type"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		Referrer           string `json:"referrer"`
+		BlockedURL         string `json:"blockedURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		OriginalPolicy     string `json:"originalPolicy"`
+		Disposition        string `json:"disposition"`
+		SourceFile         string `json:"sourceFile"`
+		Sample             string `json:"sample"`
+		LineNumber         int    `json:"lineNumber"`
+		ColumnNumber       int    `json:"columnNumber"`
+		StatusCode         int    `json:"statusCode"`
+	} `json:"body"`
+}
+
+// CSPReportHandler receives violation reports, in both the report-uri and
+// the Reporting API formats, and passes each to fn (default: log it)
+// Usage: app.POST("/csp-reports", middleware.CSPReportHandler(nil))
+func CSPReportHandler(fn func(r *http.Request, report CSPReport)) http.HandlerFunc {
+	if fn == nil {
+		fn = logCSPReport
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCSPReportSize))
+		if err != nil {
+			http.Error(w, "report too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		reports, err := parseCSPReports(r.Header.Get("Content-Type"), body)
+		if err != nil {
+			http.Error(w, "invalid report", http.StatusBadRequest)
+			return
+		}
+		for _, report := range reports {
+			fn(r, report)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func parseCSPReports(contentType string, body []byte) ([]CSPReport, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/reports+json" {
+		var batch []reportingAPIReport
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		var reports []CSPReport
+		for _, item := range batch {
+			if item.Type != "csp-violation" {
+				continue
+			}
+			b := item.Body
+			reports = append(reports, CSPReport{
+				DocumentURL:        b.DocumentURL,
+				Referrer:           b.Referrer,
+				BlockedURL:         b.BlockedURL,
+				EffectiveDirective: b.EffectiveDirective,
+				OriginalPolicy:     b.OriginalPolicy,
+				Disposition:        b.Disposition,
+				SourceFile:         b.SourceFile,
+				Sample:             b.Sample,
+				LineNumber:         b.LineNumber,
+				ColumnNumber:       b.ColumnNumber,
+				StatusCode:         b.StatusCode,
+			})
+		}
+		return reports, nil
+	}
+
+	var legacy legacyCSPReport
+	if err := json.Unmarshal(body, &legacy); err != nil {
+		return nil, err
+	}
+	l := legacy.Report
+	directive := l.EffectiveDirective
+	if directive == "" {
+		directive = l.ViolatedDirective
+	}
+	return []CSPReport{{
+		DocumentURL:        l.DocumentURI,
+		Referrer:           l.Referrer,
+		BlockedURL:         l.BlockedURI,
+		EffectiveDirective: directive,
+		OriginalPolicy:     l.OriginalPolicy,
+		Disposition:        l.Disposition,
+		SourceFile:         l.SourceFile,
+		Sample:             l.ScriptSample,
+		LineNumber:         l.LineNumber,
+		ColumnNumber:       l.ColumnNumber,
+		StatusCode:         l.StatusCode,
+	}}, nil
+}
+
+func logCSPReport(r *http.Request, report CSPReport) {
+	log.Printf("🛡️  CSP violation (%s): %s blocked %q on %s (%s:%d)",
+		report.Disposition, report.EffectiveDirective, report.BlockedURL, report.DocumentURL, report.SourceFile, report.LineNumber)
+}
@@ -0,0 +1,283 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/session"
+)
+
+// Template helpers: {{csrf_field}}, {{csrf_meta}} and {{csrf_token}}
+func init() {
+	adapters.RegisterTemplateHelper("csrf_field", func(r *http.Request) interface{} {
+		return func() template.HTML { return CSRFField(r) }
+	})
+	adapters.RegisterTemplateHelper("csrf_meta", func(r *http.Request) interface{} {
+		return func() template.HTML { return CSRFMeta(r) }
+	})
+	adapters.RegisterTemplateHelper("csrf_token", func(r *http.Request) interface{} {
+		return func() string { return CSRFToken(r) }
+	})
+}
+
+// CSRF modes
+const (
+	CSRFModeSession = "session" // Synchronizer token stored in the session (default)
+	CSRFModeCookie  = "cookie"  // Double-submit cookie, no session required
+)
+
+const csrfTokenLength = 32
+
+// ErrCSRFInvalid is reported when a request has a missing or wrong CSRF token
+var ErrCSRFInvalid = errors.New("invalid CSRF token")
+
+// CSRFConfig configures the CSRF middleware
+type CSRFConfig struct {
+	Mode       string // session (default) or cookie
+	FieldName  string // Form field (default: csrf_token)
+	HeaderName string // Header checked for AJAX requests (default: X-CSRF-Token)
+	SessionKey string // Session key in session mode (default: _csrf_token)
+	CookieName string // Cookie in cookie mode (default: _csrf)
+	Secure     bool   // Secure flag of the cookie in cookie mode
+
+	// SessionCookie is the session cookie name (default: the one of
+	// session.DefaultStore). Requests with a bearer token or an X-API-Key
+	// header and without it skip the check, nothing authenticates them
+	// implicitly.
+	SessionCookie string
+
+	// GetSession loads the session in session mode (default: session.GetSession)
+	GetSession func(r *http.Request, w http.ResponseWriter) (*session.Session, error)
+
+	// ErrorHandler renders rejected non-AJAX requests (default: plain 403)
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+type csrfContextKey struct{}
+
+// csrfState is stored in the request context for the template helpers
+type csrfState struct {
+	token     []byte
+	fieldName string
+}
+
+// CSRF protects unsafe requests (POST, PUT, PATCH, DELETE) with a token
+// that must be sent back in a form field or the X-CSRF-Token header.
+// Forms include it with {{csrf_field}} and scripts read it from
+// {{csrf_meta}} or {{csrf_token}}.
+func CSRF(config CSRFConfig) MiddlewareFunc {
+	if config.Mode == "" {
+		config.Mode = CSRFModeSession
+	}
+	if config.FieldName == "" {
+		config.FieldName = "csrf_token"
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = "X-CSRF-Token"
+	}
+	if config.SessionKey == "" {
+		config.SessionKey = "_csrf_token"
+	}
+	if config.CookieName == "" {
+		config.CookieName = "_csrf"
+	}
+	if config.GetSession == nil {
+		config.GetSession = session.GetSession
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if config.isTokenAuthenticated(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, err := config.loadToken(w, r)
+			if err != nil {
+				config.reject(w, r, err)
+				return
+			}
+
+			if !isSafeMethod(r.Method) {
+				submitted := r.Header.Get(config.HeaderName)
+				if submitted == "" {
+					submitted = r.FormValue(config.FieldName)
+				}
+				if !validToken(token, submitted) {
+					config.reject(w, r, ErrCSRFInvalid)
+					return
+				}
+			}
+
+			// Responses embedding the token must not be cached for other users
+			w.Header().Add("Vary", "Cookie")
+
+			ctx := context.WithValue(r.Context(), csrfContextKey{}, &csrfState{token: token, fieldName: config.FieldName})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// isTokenAuthenticated reports whether r is an API request carrying its
+// credentials in a header, with no session cookie a forged request could
+// ride on
+func (c CSRFConfig) isTokenAuthenticated(r *http.Request) bool {
+	// Browsers resend Basic credentials on their own, bearer tokens never
+	scheme, _, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") && r.Header.Get("X-API-Key") == "" {
+		return false
+	}
+	name := c.SessionCookie
+	if name == "" {
+		name = session.DefaultStore().Name()
+	}
+	_, err := r.Cookie(name)
+	return err != nil
+}
+
+// loadToken returns the real token for this client, creating it if needed
+func (c CSRFConfig) loadToken(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	if c.Mode == CSRFModeCookie {
+		if cookie, err := r.Cookie(c.CookieName); err == nil {
+			if token, err := base64.RawURLEncoding.DecodeString(cookie.Value); err == nil && len(token) == csrfTokenLength {
+				return token, nil
+			}
+		}
+
+		token := newCSRFToken()
+		http.SetCookie(w, &http.Cookie{
+			Name:     c.CookieName,
+			Value:    base64.RawURLEncoding.EncodeToString(token),
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   c.Secure,
+			SameSite: http.SameSiteLaxMode,
+		})
+		return token, nil
+	}
+
+	sess, err := c.GetSession(r, w)
+	if err != nil {
+		return nil, fmt.Errorf("csrf: failed to load session: %w", err)
+	}
+	if encoded := sess.GetString(c.SessionKey); encoded != "" {
+		if token, err := base64.RawURLEncoding.DecodeString(encoded); err == nil && len(token) == csrfTokenLength {
+			return token, nil
+		}
+	}
+
+	token := newCSRFToken()
+	sess.Set(c.SessionKey, base64.RawURLEncoding.EncodeToString(token))
+	if err := sess.Save(); err != nil {
+		return nil, fmt.Errorf("csrf: failed to save session: %w", err)
+	}
+	return token, nil
+}
+
+func (c CSRFConfig) reject(w http.ResponseWriter, r *http.Request, err error) {
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":  err.Error(),
+			"status": "403",
+		})
+		return
+	}
+
+	if c.ErrorHandler != nil {
+		c.ErrorHandler(w, r, err)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusForbidden)
+}
+
+// CSRFToken returns a masked token for the current request, or "" when the
+// CSRF middleware didn't run. Masking gives every response a different
+// value so the token can't be recovered through compression (BREACH).
+func CSRFToken(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	state, ok := r.Context().Value(csrfContextKey{}).(*csrfState)
+	if !ok {
+		return ""
+	}
+	return maskToken(state.token)
+}
+
+// CSRFField returns a hidden input with the token for HTML forms
+func CSRFField(r *http.Request) template.HTML {
+	token := CSRFToken(r)
+	if token == "" {
+		return ""
+	}
+	state := r.Context().Value(csrfContextKey{}).(*csrfState)
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+		template.HTMLEscapeString(state.fieldName), token))
+}
+
+// CSRFMeta returns a meta tag with the token for JavaScript requests
+func CSRFMeta(r *http.Request) template.HTML {
+	token := CSRFToken(r)
+	if token == "" {
+		return ""
+	}
+	return template.HTML(fmt.Sprintf(`<meta name="csrf-token" content="%s">`, token))
+}
+
+func newCSRFToken() []byte {
+	token := make([]byte, csrfTokenLength)
+	rand.Read(token)
+	return token
+}
+
+// maskToken returns base64(otp || otp XOR token)
+func maskToken(token []byte) string {
+	otp := newCSRFToken()
+	masked := make([]byte, 0, csrfTokenLength*2)
+	masked = append(masked, otp...)
+	for i := range token {
+		masked = append(masked, otp[i]^token[i])
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+// validToken unmasks submitted and compares it with token in constant time
+func validToken(token []byte, submitted string) bool {
+	masked, err := base64.RawURLEncoding.DecodeString(submitted)
+	if err != nil || len(masked) != csrfTokenLength*2 {
+		return false
+	}
+
+	otp, value := masked[:csrfTokenLength], masked[csrfTokenLength:]
+	unmasked := make([]byte, csrfTokenLength)
+	for i := range value {
+		unmasked[i] = otp[i] ^ value[i]
+	}
+	return subtle.ConstantTimeCompare(unmasked, token) == 1
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// wantsJSON reports whether the client is an AJAX or API request
+func wantsJSON(r *http.Request) bool {
+	return r.Header.Get("X-Requested-With") == "XMLHttpRequest" ||
+		strings.Contains(r.Header.Get("Accept"), "application/json") ||
+		strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+}
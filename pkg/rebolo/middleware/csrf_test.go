@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/session"
+)
+
+func TestValidToken(t *testing.T) {
+	token := newCSRFToken()
+	masked := maskToken(token)
+	truncated, _ := base64.RawURLEncoding.DecodeString(masked)
+
+	tests := []struct {
+		name      string
+		submitted string
+		want      bool
+	}{
+		{"masked token", masked, true},
+		{"masked again", maskToken(token), true},
+		{"other token", maskToken(newCSRFToken()), false},
+		{"unmasked token", base64.RawURLEncoding.EncodeToString(token), false},
+		{"truncated", base64.RawURLEncoding.EncodeToString(truncated[:csrfTokenLength*2-1]), false},
+		{"not base64", "!" + masked[1:], false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		if got := validToken(token, tt.submitted); got != tt.want {
+			t.Errorf("%s: validToken() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if maskToken(token) == maskToken(token) {
+		t.Error("maskToken returned the same value twice, responses would leak the token (BREACH)")
+	}
+}
+
+// csrfClient is a browser with its own cookies talking to a server
+// protected by the CSRF middleware, which echoes the token on GET
+type csrfClient struct {
+	t      *testing.T
+	server *httptest.Server
+	client *http.Client
+}
+
+func newCSRFClient(t *testing.T, mode string) *csrfClient {
+	t.Helper()
+
+	store := session.NewMemorySessionStore("test_session", session.KeyPairs("0123456789abcdef0123456789abcdef")...)
+	t.Cleanup(func() { store.Close() })
+	csrf := CSRF(CSRFConfig{Mode: mode, GetSession: store.Get, SessionCookie: "test_session"})
+
+	server := httptest.NewServer(csrf(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mode == CSRFModeCookie {
+			// Cookie mode needs no session, but the app may still use one
+			http.SetCookie(w, &http.Cookie{Name: "test_session", Value: "1", Path: "/"})
+		}
+		io.WriteString(w, CSRFToken(r))
+	})))
+	t.Cleanup(server.Close)
+
+	jar, _ := cookiejar.New(nil)
+	return &csrfClient{t: t, server: server, client: &http.Client{Jar: jar}}
+}
+
+func (c *csrfClient) do(req *http.Request) (int, string) {
+	c.t.Helper()
+	res, err := c.client.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(body)
+}
+
+// token loads a page and returns the masked token it rendered
+func (c *csrfClient) token() string {
+	c.t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, c.server.URL, nil)
+	status, token := c.do(req)
+	if status != http.StatusOK || token == "" {
+		c.t.Fatalf("GET: got status %d and token %q", status, token)
+	}
+	return token
+}
+
+func TestCSRF(t *testing.T) {
+	tests := []struct {
+		name    string
+		request func(c *csrfClient) *http.Request
+		want    int
+	}{
+		{
+			name: "form field",
+			request: func(c *csrfClient) *http.Request {
+				form := url.Values{"csrf_token": {c.token()}}
+				req, _ := http.NewRequest(http.MethodPost, c.server.URL, strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "header",
+			request: func(c *csrfClient) *http.Request {
+				req, _ := http.NewRequest(http.MethodDelete, c.server.URL, nil)
+				req.Header.Set("X-CSRF-Token", c.token())
+				return req
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "missing token",
+			request: func(c *csrfClient) *http.Request {
+				c.token()
+				req, _ := http.NewRequest(http.MethodPost, c.server.URL, nil)
+				return req
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "token of another client",
+			request: func(c *csrfClient) *http.Request {
+				other := &csrfClient{t: c.t, server: c.server, client: &http.Client{}}
+				other.client.Jar, _ = cookiejar.New(nil)
+				c.token()
+				req, _ := http.NewRequest(http.MethodPost, c.server.URL, nil)
+				req.Header.Set("X-CSRF-Token", other.token())
+				return req
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "no cookie yet",
+			request: func(c *csrfClient) *http.Request {
+				req, _ := http.NewRequest(http.MethodPut, c.server.URL, nil)
+				req.Header.Set("X-CSRF-Token", maskToken(newCSRFToken()))
+				return req
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "bearer token without session cookie",
+			request: func(c *csrfClient) *http.Request {
+				req, _ := http.NewRequest(http.MethodPost, c.server.URL, nil)
+				req.Header.Set("Authorization", "Bearer abc")
+				return req
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "API key with session cookie",
+			request: func(c *csrfClient) *http.Request {
+				c.token()
+				req, _ := http.NewRequest(http.MethodPost, c.server.URL, nil)
+				req.Header.Set("X-API-Key", "abc")
+				return req
+			},
+			want: http.StatusForbidden,
+		},
+		{
+			name: "basic auth",
+			request: func(c *csrfClient) *http.Request {
+				req, _ := http.NewRequest(http.MethodPost, c.server.URL, nil)
+				req.SetBasicAuth("user", "secret")
+				return req
+			},
+			want: http.StatusForbidden,
+		},
+	}
+
+	for _, mode := range []string{CSRFModeSession, CSRFModeCookie} {
+		for _, tt := range tests {
+			t.Run(mode+"/"+tt.name, func(t *testing.T) {
+				c := newCSRFClient(t, mode)
+				if status, body := c.do(tt.request(c)); status != tt.want {
+					t.Errorf("got status %d (%s), want %d", status, strings.TrimSpace(body), tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestCSRFRejectsJSON(t *testing.T) {
+	c := newCSRFClient(t, CSRFModeSession)
+	req, _ := http.NewRequest(http.MethodPost, c.server.URL, strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+
+	status, body := c.do(req)
+	if status != http.StatusForbidden || !strings.Contains(body, `"error":"invalid CSRF token"`) {
+		t.Errorf("got status %d and body %s, want a JSON 403", status, body)
+	}
+}
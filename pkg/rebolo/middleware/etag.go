@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/httpcache"
+)
+
+// ETagConfig configures ETag
+type ETagConfig struct {
+	// ContentTypes are the content type prefixes tagged (default: HTML and
+	// JSON)
+	ContentTypes []string
+
+	// MaxSize is the largest body buffered to be hashed, larger ones are
+	// streamed untagged (default: 1MB)
+	MaxSize int
+}
+
+// ETag adds a weak ETag, hashed from the body, to successful GET and HEAD
+// responses, and answers 304 Not Modified when it matches If-None-Match.
+// The page is still rendered, but not sent again. Responses with an ETag
+// already (see httpcache.Fresh) and streamed ones are left alone.
+// The CSP nonce doesn't count in the hash, and 304s don't send a new CSP,
+// so the browser keeps the one matching its copy. Pages with a CSRF field
+// change on every request and never match.
+// Usage: app.Use(middleware.ETag())
+func ETag(configs ...ETagConfig) MiddlewareFunc {
+	var config ETagConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if len(config.ContentTypes) == 0 {
+		config.ContentTypes = []string{"text/html", "application/json"}
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = 1 << 20
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ew := &etagWriter{ResponseWriter: w, r: r, config: &config, status: http.StatusOK}
+			next.ServeHTTP(ew, r)
+			ew.Close()
+		})
+	}
+}
+
+// etagWriter buffers eligible responses until the handler returns
+type etagWriter struct {
+	http.ResponseWriter
+	r      *http.Request
+	config *ETagConfig
+
+	status  int
+	buf     bytes.Buffer
+	decided bool // Headers were sent, the rest is passed through
+}
+
+func (ew *etagWriter) WriteHeader(code int) {
+	if ew.decided {
+		return
+	}
+	// Informational responses go out right away
+	if code >= 100 && code < 200 {
+		ew.ResponseWriter.WriteHeader(code)
+		return
+	}
+	ew.status = code
+	if !ew.eligible() {
+		ew.start()
+	}
+}
+
+func (ew *etagWriter) Write(b []byte) (int, error) {
+	if !ew.decided {
+		if !ew.eligible() || ew.buf.Len()+len(b) > ew.config.MaxSize {
+			ew.start()
+			if err := ew.writeBuffered(); err != nil {
+				return 0, err
+			}
+		} else {
+			return ew.buf.Write(b)
+		}
+	}
+	return ew.ResponseWriter.Write(b)
+}
+
+// eligible reports whether the response may be tagged, judging by its
+// status and headers
+func (ew *etagWriter) eligible() bool {
+	if ew.status != http.StatusOK {
+		return false
+	}
+	header := ew.Header()
+	if header.Get("ETag") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	// Unset content types are sniffed when the body is complete
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	if contentType == "" {
+		return true
+	}
+	for _, prefix := range ew.config.ContentTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// start sends the headers untagged
+func (ew *etagWriter) start() {
+	ew.decided = true
+	ew.ResponseWriter.WriteHeader(ew.status)
+}
+
+func (ew *etagWriter) writeBuffered() error {
+	if ew.buf.Len() == 0 {
+		return nil
+	}
+	_, err := ew.ResponseWriter.Write(ew.buf.Bytes())
+	ew.buf.Reset()
+	return err
+}
+
+// Close tags the buffered response, or answers 304 when the client has it
+func (ew *etagWriter) Close() error {
+	if ew.decided {
+		return nil
+	}
+	ew.decided = true
+
+	header := ew.Header()
+	body := ew.buf.Bytes()
+	if header.Get("Content-Type") == "" && len(body) > 0 {
+		header.Set("Content-Type", http.DetectContentType(body))
+	}
+	if len(body) == 0 || !ew.eligible() {
+		ew.ResponseWriter.WriteHeader(ew.status)
+		return ew.writeBuffered()
+	}
+
+	hashed := body
+	if nonce := CSPNonce(ew.r); nonce != "" {
+		hashed = bytes.ReplaceAll(body, []byte(nonce), nil)
+	}
+	etag := httpcache.WeakETag(hashed)
+	header.Set("ETag", etag)
+
+	// A Last-Modified set by the handler answers If-Modified-Since too
+	lastModified, _ := http.ParseTime(header.Get("Last-Modified"))
+	if httpcache.IsFresh(ew.r, etag, lastModified) {
+		httpcache.NotModified(ew.ResponseWriter)
+		return nil
+	}
+
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	ew.ResponseWriter.WriteHeader(ew.status)
+	return ew.writeBuffered()
+}
+
+// Flush sends what was written so far untagged, for streaming responses
+func (ew *etagWriter) Flush() {
+	if !ew.decided {
+		ew.start()
+		ew.writeBuffered()
+	}
+	if flusher, ok := ew.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack supports websockets through the middleware
+func (ew *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := ew.ResponseWriter.(http.Hijacker)
+	if !ok || ew.decided {
+		return nil, nil, fmt.Errorf("etag: response can't be hijacked")
+	}
+	ew.decided = true
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
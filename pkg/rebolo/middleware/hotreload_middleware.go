@@ -43,6 +43,7 @@ type responseWriter struct {
 	http.ResponseWriter
 	body       *bytes.Buffer
 	statusCode int
+	streaming  bool // Flushed by the handler, written through as is
 }
 
 func newResponseWriter(w http.ResponseWriter) *responseWriter {
@@ -54,21 +55,40 @@ func newResponseWriter(w http.ResponseWriter) *responseWriter {
 }
 
 func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.streaming {
+		return rw.ResponseWriter.Write(b)
+	}
 	return rw.body.Write(b)
 }
 
 func (rw *responseWriter) WriteHeader(statusCode int) {
-	rw.statusCode = statusCode
+	if !rw.streaming {
+		rw.statusCode = statusCode
+	}
 }
 
+// Flush is called by streaming handlers: the script can't be injected
+// anymore, so send what was buffered and write the rest through
 func (rw *responseWriter) Flush() {
+	if !rw.streaming {
+		rw.streaming = true
+		rw.finish()
+	}
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// finish writes the status and the buffered body
+func (rw *responseWriter) finish() {
 	// Remove Content-Length as we're modifying the body
 	rw.Header().Del("Content-Length")
 
-	// Write actual headers
-	for k, v := range rw.Header() {
-		rw.ResponseWriter.Header()[k] = v
-	}
 	rw.ResponseWriter.WriteHeader(rw.statusCode)
 
 	// Write body
@@ -98,23 +118,33 @@ func HotReloadMiddleware(enabled bool, skipPaths ...string) func(http.Handler) h
 			// Call next handler
 			next.ServeHTTP(rw, r)
 
+			if rw.streaming {
+				return
+			}
+
 			// Get content type
 			contentType := rw.Header().Get("Content-Type")
 
-			// Only inject script into HTML responses
-			if strings.Contains(contentType, "text/html") {
+			// Only inject script into HTML responses (not already compressed
+			// ones, Compress runs outside this middleware)
+			if strings.Contains(contentType, "text/html") && rw.Header().Get("Content-Encoding") == "" {
 				body := rw.body.String()
 
 				// Inject script before </body>
 				if idx := strings.LastIndex(body, "</body>"); idx != -1 {
-					body = body[:idx] + HotReloadScript + body[idx:]
+					script := HotReloadScript
+					// Let the script run under the CSP of SecureHeaders
+					if nonce := CSPNonce(r); nonce != "" {
+						script = strings.Replace(script, "<script>", `<script nonce="`+nonce+`">`, 1)
+					}
+					body = body[:idx] + script + body[idx:]
 					rw.body.Reset()
 					rw.body.WriteString(body)
 				}
 			}
 
-			// Flush response
-			rw.Flush()
+			// Send response
+			rw.finish()
 		})
 	}
 }
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	rerrors "github.com/Palaciodiego008/rebololang/pkg/rebolo/errors"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/maintenance"
+)
+
+// MaintenanceBypassParam is the query parameter exchanging the bypass
+// secret for the bypass cookie: /?maintenance=<secret>
+const MaintenanceBypassParam = "maintenance"
+
+// MaintenanceConfig configures Maintenance
+type MaintenanceConfig struct {
+	Store maintenance.Store // Required
+
+	// AllowIPs are let through, besides the ones of the maintenance state
+	AllowIPs []string
+
+	// Skip lists paths served during maintenance, with the Skip patterns of
+	// Use (default: /__rebolo__/*)
+	Skip []string
+
+	// CheckInterval is how often the store is read (default: 2s)
+	CheckInterval time.Duration
+
+	// OnMaintenance writes the response of refused requests (default: plain
+	// text error). err is a 503 HTTPError wrapping a *maintenance.Error.
+	OnMaintenance func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// Maintenance answers 503 Service Unavailable, with Retry-After, while the
+// store holds a maintenance state (see rebolo maintenance on). Allowed IPs
+// and browsers with the bypass cookie still get through: staff open any
+// page with ?maintenance=<secret> once to get it.
+// Usage: app.AddMiddleware(middleware.Maintenance(middleware.MaintenanceConfig{Store: maintenance.NewFileStore("")}))
+func Maintenance(config MaintenanceConfig) MiddlewareFunc {
+	if config.Skip == nil {
+		config.Skip = []string{"/__rebolo__/*"}
+	}
+	if config.OnMaintenance == nil {
+		config.OnMaintenance = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), rerrors.StatusCode(err))
+		}
+	}
+	checker := maintenance.NewChecker(config.Store, config.CheckInterval)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			state := checker.State(r.Context())
+			if state == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, pattern := range config.Skip {
+				if matchPath(r.URL.Path, pattern) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			if secret := r.URL.Query().Get(MaintenanceBypassParam); secret != "" && state.AllowsSecret(secret) {
+				http.SetCookie(w, &http.Cookie{
+					Name:     maintenance.CookieName,
+					Value:    secret,
+					Path:     "/",
+					HttpOnly: true,
+					Secure:   r.TLS != nil,
+					SameSite: http.SameSiteLaxMode,
+				})
+				// Drop the secret from the URL
+				target := *r.URL
+				query := target.Query()
+				query.Del(MaintenanceBypassParam)
+				target.RawQuery = query.Encode()
+				http.Redirect(w, r, target.RequestURI(), http.StatusSeeOther)
+				return
+			}
+			if cookie, err := r.Cookie(maintenance.CookieName); err == nil && state.AllowsSecret(cookie.Value) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ip, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				ip = r.RemoteAddr
+			}
+			if state.AllowsIP(ip, config.AllowIPs...) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			retryAfter := state.RetryAfter
+			if retryAfter <= 0 {
+				retryAfter = 5 * time.Minute
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter/time.Second)))
+			w.Header().Set("Cache-Control", "no-store")
+			config.OnMaintenance(w, r, rerrors.NewHTTPError(http.StatusServiceUnavailable, &maintenance.Error{State: state}))
+		})
+	}
+}
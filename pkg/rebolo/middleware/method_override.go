@@ -1,7 +1,11 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/validation"
 )
 
 // MethodOverride middleware allows HTML forms to use PUT, PATCH, and DELETE methods
@@ -13,7 +17,21 @@ func MethodOverride(next http.Handler) http.Handler {
 		if r.Method == "POST" {
 			// Try to parse form to get _method field
 			// ParseForm() will parse both URL query and POST body
-			_ = r.ParseForm()
+			var err error
+			if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+				// Same memory limit as Bind, larger files go to temp files
+				err = r.ParseMultipartForm(validation.MaxMultipartMemory)
+			} else {
+				err = r.ParseForm()
+			}
+
+			// Bodies over MaxBodySize are left unparsed, so Bind reports it
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				r.Form, r.PostForm = nil, nil
+				next.ServeHTTP(w, r)
+				return
+			}
 
 			// Get _method from form values (works with both Form and PostForm)
 			method := r.FormValue("_method")
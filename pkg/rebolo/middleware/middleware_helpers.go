@@ -1,63 +1,36 @@
 package middleware
 
 import (
-	"net/http"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/auth"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/ratelimit"
 )
 
 // Common middleware examples
 
-// CORSMiddleware adds CORS headers
+// CORSMiddleware allows requests from allowOrigin (see CORS for the other
+// options)
 func CORSMiddleware(allowOrigin string) MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-			
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-			
-			next.ServeHTTP(w, r)
-		})
-	}
+	return CORS(CORSConfig{AllowOrigins: []string{allowOrigin}})
 }
 
-// AuthMiddleware checks if user is authenticated (example)
+// AuthMiddleware only lets logged in users through (see auth.Authenticator).
+// HTML requests are redirected to redirectTo and JSON requests get a 401.
 func AuthMiddleware(redirectTo string) MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// This is a simple example - you'd implement your own auth logic
-			// For now, we'll check if there's a session with "authenticated" = true
-			
-			// You'd need to get the session store from context or app
-			// For simplicity, we'll skip this check for now
-			// In a real implementation, you'd inject the app or session store
-			
-			next.ServeHTTP(w, r)
-		})
-	}
+	return auth.New(auth.Config{LoginPath: redirectTo}).RequireLogin
 }
 
-// RateLimitMiddleware implements simple rate limiting (placeholder)
+// RateLimitMiddleware allows requestsPerMinute requests per client IP,
+// counted in memory (see the ratelimit package for other limiters, keys
+// and shared stores)
 func RateLimitMiddleware(requestsPerMinute int) MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Implement rate limiting logic here
-			// For now, just pass through
-			next.ServeHTTP(w, r)
-		})
-	}
+	return ratelimit.Middleware(ratelimit.Config{
+		Limiter: ratelimit.NewSlidingWindow(nil, requestsPerMinute, time.Minute),
+	})
 }
 
-// GzipMiddleware adds gzip compression (placeholder)
+// GzipMiddleware compresses responses with gzip or deflate (see Compress)
 func GzipMiddleware() MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Implement gzip compression here
-			// For now, just pass through
-			next.ServeHTTP(w, r)
-		})
-	}
+	return Compress()
 }
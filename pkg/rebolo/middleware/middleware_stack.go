@@ -88,9 +88,10 @@ func matchPath(path, pattern string) bool {
 	return matched
 }
 
-// Apply applies all middleware in the stack to a handler
+// Apply applies all middleware in the stack to a handler, the first
+// registered outermost so they run in registration order
 func (ms *MiddlewareStack) Apply(handler http.Handler) http.Handler {
-	// Apply middleware in reverse order (last registered = outermost)
+	// Wrap in reverse order (first registered = outermost)
 	for i := len(ms.middlewares) - 1; i >= 0; i-- {
 		config := ms.middlewares[i]
 		handler = ms.wrapWithSkip(config, handler)
@@ -100,12 +101,13 @@ func (ms *MiddlewareStack) Apply(handler http.Handler) http.Handler {
 
 // wrapWithSkip wraps a handler with middleware that can be skipped
 func (ms *MiddlewareStack) wrapWithSkip(config *MiddlewareConfig, next http.Handler) http.Handler {
+	wrapped := config.handler(next)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if config.shouldSkip(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
-		config.handler(next).ServeHTTP(w, r)
+		wrapped.ServeHTTP(w, r)
 	})
 }
 
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/logging"
+)
+
+// RequestIDHeader is the default request ID header
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDConfig configures RequestID
+type RequestIDConfig struct {
+	Header string // Default: X-Request-ID
+
+	// IgnoreInbound always generates a new ID, for apps not behind a proxy
+	// or load balancer that sets it
+	IgnoreInbound bool
+
+	// Generator creates new IDs (default: 32 random hex characters)
+	Generator func() string
+}
+
+// validRequestID limits the inbound IDs that are kept, so clients can't
+// inject anything into the logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:/+=-]{1,128}$`)
+
+// RequestID gives every request an ID, kept from the inbound header when
+// valid and sent back in the response. Handlers read it with
+// RequestIDFrom, logs and worker jobs get it from the request context.
+// Usage: app.AddMiddleware(middleware.RequestID())
+func RequestID(configs ...RequestIDConfig) MiddlewareFunc {
+	var config RequestIDConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.Header == "" {
+		config.Header = RequestIDHeader
+	}
+	if config.Generator == nil {
+		config.Generator = newRequestID
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := ""
+			if !config.IgnoreInbound {
+				if inbound := r.Header.Get(config.Header); validRequestID.MatchString(inbound) {
+					id = inbound
+				}
+			}
+			if id == "" {
+				id = config.Generator()
+			}
+
+			w.Header().Set(config.Header, id)
+			next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+		})
+	}
+}
+
+// RequestIDFrom returns the ID of the request, or "" when RequestID didn't run
+func RequestIDFrom(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	return logging.RequestID(r.Context())
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,303 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+)
+
+// Template helper: <script nonce="{{csp_nonce}}">
+func init() {
+	adapters.RegisterTemplateHelper("csp_nonce", func(r *http.Request) interface{} {
+		return func() string { return CSPNonce(r) }
+	})
+}
+
+// SecureHeadersConfig configures SecureHeaders. Empty string fields use
+// their default, "-" leaves the header out.
+type SecureHeadersConfig struct {
+	// HSTSMaxAge enables Strict-Transport-Security (app.EnableSecureHeaders
+	// sets one year in production)
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+
+	FrameOptions            string // X-Frame-Options (default: DENY)
+	ContentTypeOptions      string // X-Content-Type-Options (default: nosniff)
+	ReferrerPolicy          string // Referrer-Policy (default: strict-origin-when-cross-origin)
+	CrossOriginOpenerPolicy string // Cross-Origin-Opener-Policy (default: same-origin)
+	PermissionsPolicy       string // Permissions-Policy (not sent by default)
+
+	// CSP is the Content-Security-Policy, not sent when nil (see DefaultCSP)
+	CSP *CSP
+
+	// CSPReportOnly sends Content-Security-Policy-Report-Only instead, to
+	// try a policy out without breaking pages
+	CSPReportOnly bool
+
+	// OnReport receives the violations posted to the report path of the CSP
+	// (app.EnableSecureHeaders routes it, default: log them)
+	OnReport func(r *http.Request, report CSPReport)
+}
+
+// CSPNonceSource is replaced by the nonce of the request ('nonce-...') in
+// CSP directives
+const CSPNonceSource = "'nonce'"
+
+// CSP builds a Content-Security-Policy
+// Usage: middleware.DefaultCSP().Add("img-src", "https://images.example.com")
+type CSP struct {
+	directives []cspDirective
+	reportTo   string // Reporting-Endpoints group
+}
+
+type cspDirective struct {
+	name    string
+	sources []string
+}
+
+// NewCSP creates an empty policy
+func NewCSP() *CSP {
+	return &CSP{}
+}
+
+// DefaultCSP allows resources from the app origin only, and inline scripts
+// carrying the request nonce. Inline styles are allowed, style attributes
+// can't carry a nonce.
+func DefaultCSP() *CSP {
+	return NewCSP().
+		Set("default-src", "'self'").
+		Set("script-src", "'self'", CSPNonceSource).
+		Set("style-src", "'self'", "'unsafe-inline'").
+		Set("img-src", "'self'", "data:").
+		Set("font-src", "'self'", "data:").
+		Set("connect-src", "'self'").
+		Set("object-src", "'none'").
+		Set("base-uri", "'self'").
+		Set("form-action", "'self'").
+		Set("frame-ancestors", "'none'")
+}
+
+// Set replaces the sources of a directive
+func (c *CSP) Set(directive string, sources ...string) *CSP {
+	directive = strings.ToLower(directive)
+	for i := range c.directives {
+		if c.directives[i].name == directive {
+			c.directives[i].sources = append([]string(nil), sources...)
+			return c
+		}
+	}
+	c.directives = append(c.directives, cspDirective{name: directive, sources: append([]string(nil), sources...)})
+	return c
+}
+
+// Add appends sources to a directive, skipping the ones already there
+func (c *CSP) Add(directive string, sources ...string) *CSP {
+	directive = strings.ToLower(directive)
+	for i := range c.directives {
+		if c.directives[i].name != directive {
+			continue
+		}
+		for _, source := range sources {
+			if !containsString(c.directives[i].sources, source) {
+				c.directives[i].sources = append(c.directives[i].sources, source)
+			}
+		}
+		return c
+	}
+	return c.Set(directive, sources...)
+}
+
+// Remove drops a directive
+func (c *CSP) Remove(directive string) *CSP {
+	directive = strings.ToLower(directive)
+	for i := range c.directives {
+		if c.directives[i].name == directive {
+			c.directives = append(c.directives[:i], c.directives[i+1:]...)
+			break
+		}
+	}
+	return c
+}
+
+// ReportTo sends violation reports to path, with both report-uri and the
+// Reporting API (report-to)
+func (c *CSP) ReportTo(path string) *CSP {
+	c.reportTo = "csp-endpoint"
+	c.Set("report-uri", path)
+	return c.Set("report-to", c.reportTo)
+}
+
+// ReportPath returns the report-uri of the policy, or ""
+func (c *CSP) ReportPath() string {
+	for _, d := range c.directives {
+		if d.name == "report-uri" && len(d.sources) > 0 {
+			return d.sources[0]
+		}
+	}
+	return ""
+}
+
+// Clone returns a copy of the policy
+func (c *CSP) Clone() *CSP {
+	clone := &CSP{reportTo: c.reportTo}
+	for _, d := range c.directives {
+		clone.directives = append(clone.directives, cspDirective{name: d.name, sources: append([]string(nil), d.sources...)})
+	}
+	return clone
+}
+
+// UsesNonce reports whether a directive has CSPNonceSource
+func (c *CSP) UsesNonce() bool {
+	for _, d := range c.directives {
+		if containsString(d.sources, CSPNonceSource) {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the policy with nonce in place of CSPNonceSource
+func (c *CSP) String(nonce string) string {
+	parts := make([]string, 0, len(c.directives))
+	for _, d := range c.directives {
+		var b strings.Builder
+		b.WriteString(d.name)
+		for _, source := range d.sources {
+			if source == CSPNonceSource {
+				if nonce == "" {
+					continue
+				}
+				source = "'nonce-" + nonce + "'"
+			}
+			b.WriteString(" ")
+			b.WriteString(source)
+		}
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type cspNonceContextKey struct{}
+
+// CSPNonce returns the nonce of the current request, or "" when
+// SecureHeaders didn't run or its CSP has no nonce
+func CSPNonce(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	nonce, _ := r.Context().Value(cspNonceContextKey{}).(string)
+	return nonce
+}
+
+// SecureHeaders sets the security headers of every response. With a CSP
+// using CSPNonceSource, each request gets a new nonce for its inline
+// scripts ({{csp_nonce}} in templates, CSPNonce in handlers).
+// Usage: app.Use(middleware.SecureHeaders(middleware.SecureHeadersConfig{CSP: middleware.DefaultCSP()}))
+func SecureHeaders(config SecureHeadersConfig) MiddlewareFunc {
+	static := map[string]string{
+		"X-Frame-Options":            headerValue(config.FrameOptions, "DENY"),
+		"X-Content-Type-Options":     headerValue(config.ContentTypeOptions, "nosniff"),
+		"Referrer-Policy":            headerValue(config.ReferrerPolicy, "strict-origin-when-cross-origin"),
+		"Cross-Origin-Opener-Policy": headerValue(config.CrossOriginOpenerPolicy, "same-origin"),
+		"Permissions-Policy":         headerValue(config.PermissionsPolicy, ""),
+	}
+	if config.HSTSMaxAge > 0 {
+		hsts := fmt.Sprintf("max-age=%d", int(config.HSTSMaxAge.Seconds()))
+		if config.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if config.HSTSPreload {
+			hsts += "; preload"
+		}
+		static["Strict-Transport-Security"] = hsts
+	}
+
+	var (
+		csp            = config.CSP
+		cspHeader      = "Content-Security-Policy"
+		reportingGroup string
+		reportingPath  string
+	)
+	if csp != nil {
+		csp = csp.Clone()
+		if config.CSPReportOnly {
+			cspHeader = "Content-Security-Policy-Report-Only"
+		}
+		if csp.reportTo != "" {
+			reportingGroup, reportingPath = csp.reportTo, csp.ReportPath()
+		}
+	}
+	useNonce := csp != nil && csp.UsesNonce()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			for name, value := range static {
+				if value != "" {
+					header.Set(name, value)
+				}
+			}
+
+			if csp != nil {
+				nonce := ""
+				if useNonce {
+					nonce = newCSPNonce()
+					r = r.WithContext(context.WithValue(r.Context(), cspNonceContextKey{}, nonce))
+				}
+				header.Set(cspHeader, csp.String(nonce))
+				if reportingGroup != "" {
+					header.Set("Reporting-Endpoints", fmt.Sprintf(`%s="%s"`, reportingGroup, absoluteURL(r, reportingPath)))
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// headerValue applies the "" (default) and "-" (disabled) conventions
+func headerValue(value, defaultValue string) string {
+	switch value {
+	case "":
+		return defaultValue
+	case "-":
+		return ""
+	}
+	return value
+}
+
+// absoluteURL resolves path against the request host, the Reporting API
+// needs absolute URLs
+func absoluteURL(r *http.Request, path string) string {
+	if strings.Contains(path, "://") {
+		return path
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + path
+}
+
+// newCSPNonce returns a URL-safe nonce, which templates output unescaped
+func newCSPNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	rerrors "github.com/Palaciodiego008/rebololang/pkg/rebolo/errors"
+)
+
+// ErrTimeout is reported for requests that took longer than their timeout
+var ErrTimeout = errors.New("request timed out")
+
+// TimeoutConfig configures Timeout
+type TimeoutConfig struct {
+	Timeout time.Duration // Required
+
+	// OnTimeout writes the response of timed out requests (default: plain
+	// text error). err is an HTTPError with StatusCode.
+	OnTimeout func(w http.ResponseWriter, r *http.Request, err error)
+
+	// StatusCode of timed out requests (default: 503 Service Unavailable)
+	StatusCode int
+}
+
+// Timeout cancels the request context after config.Timeout and answers
+// with OnTimeout if the handler didn't finish by then. The response is
+// buffered until the handler returns, so it can't stream.
+// Usage: group.Use(middleware.Timeout(middleware.TimeoutConfig{Timeout: 5 * time.Second}))
+func Timeout(config TimeoutConfig) MiddlewareFunc {
+	if config.StatusCode == 0 {
+		config.StatusCode = http.StatusServiceUnavailable
+	}
+	if config.OnTimeout == nil {
+		config.OnTimeout = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), rerrors.StatusCode(err))
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		if config.Timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), config.Timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{w: w, header: make(http.Header), status: http.StatusOK}
+			done := make(chan struct{})
+			panicked := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				// Let RecoveryMiddleware handle it in this goroutine
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.flush()
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				// The handler may still finish first if the parent was canceled
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					tw.timedOut = true
+					config.OnTimeout(w, r, rerrors.NewHTTPError(config.StatusCode, ErrTimeout))
+					return
+				}
+				tw.timedOut = true
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers the response until the handler returns
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, fmt.Errorf("timeout: %w", ErrTimeout)
+	}
+	tw.wroteHeader = true
+	return tw.buf.Write(b)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.status = code
+	tw.wroteHeader = true
+}
+
+// flush sends the buffered response (with tw.mu held)
+func (tw *timeoutWriter) flush() {
+	header := tw.w.Header()
+	for name, values := range tw.header {
+		header[name] = values
+	}
+	tw.w.WriteHeader(tw.status)
+	tw.w.Write(tw.buf.Bytes())
+}
@@ -0,0 +1,126 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	rerrors "github.com/Palaciodiego008/rebololang/pkg/rebolo/errors"
+)
+
+// ErrForbidden is reported when a policy denies an action
+var ErrForbidden = errors.New("forbidden")
+
+// Policy decides whether a user may perform an action on a record. user is
+// nil for anonymous requests and record may be a zero value (e.g.
+// &models.Post{}) for actions without a record such as list or create.
+type Policy interface {
+	Can(user interface{}, action string, record interface{}) bool
+}
+
+// Func adapts a function to a Policy
+// Usage: policy.Func(func(user interface{}, action string, record interface{}) bool { ... })
+type Func func(user interface{}, action string, record interface{}) bool
+
+// Can calls f
+func (f Func) Can(user interface{}, action string, record interface{}) bool {
+	return f(user, action, record)
+}
+
+// Any allows an action if any of policies allows it
+// Usage: policy.Any(policy.Roles{"*": {"admin"}}, PostPolicy{})
+func Any(policies ...Policy) Policy {
+	return Func(func(user interface{}, action string, record interface{}) bool {
+		for _, p := range policies {
+			if p.Can(user, action, record) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Permissions declares the policy action each controller or resource
+// action requires. Controller actions are index, show, new, create, edit,
+// update and delete; resource actions are list, show, create, update and
+// destroy. Actions not listed aren't checked.
+type Permissions struct {
+	Model   interface{}       // Record type the policy is registered for, e.g. &models.Post{}
+	Actions map[string]string // e.g. {"create": "create", "destroy": "delete"}
+}
+
+// Protected can be implemented by a core.Controller or resource.Resource to
+// have its actions checked against the policy of Permissions().Model before
+// they run
+type Protected interface {
+	Permissions() Permissions
+}
+
+// Registry holds the policy of each model type
+type Registry struct {
+	mu       sync.RWMutex
+	policies map[reflect.Type]Policy
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{policies: make(map[reflect.Type]Policy)}
+}
+
+// Register sets the policy for the type of model. Pointers and values of the
+// same type share a policy.
+// Usage: registry.Register(&models.Post{}, PostPolicy{})
+func (r *Registry) Register(model interface{}, p Policy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policies[modelType(model)] = p
+}
+
+// Policy returns the policy registered for the type of record
+func (r *Registry) Policy(record interface{}) (Policy, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.policies[modelType(record)]
+	return p, ok
+}
+
+// Can reports whether user may perform action on record. Records without a
+// registered policy are denied.
+func (r *Registry) Can(user interface{}, action string, record interface{}) bool {
+	p, ok := r.Policy(record)
+	if !ok {
+		return false
+	}
+	return p.Can(user, action, record)
+}
+
+// Authorize returns a 403 HTTPError wrapping ErrForbidden unless user may
+// perform action on record
+func (r *Registry) Authorize(user interface{}, action string, record interface{}) error {
+	if _, ok := r.Policy(record); !ok {
+		return rerrors.NewHTTPError(http.StatusForbidden,
+			fmt.Errorf("%w: no policy registered for %s", ErrForbidden, modelName(record)))
+	}
+	if !r.Can(user, action, record) {
+		return rerrors.NewHTTPError(http.StatusForbidden,
+			fmt.Errorf("%w: not allowed to %s %s", ErrForbidden, action, modelName(record)))
+	}
+	return nil
+}
+
+func modelType(model interface{}) reflect.Type {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func modelName(model interface{}) string {
+	if t := modelType(model); t != nil {
+		return t.Name()
+	}
+	return "<nil>"
+}
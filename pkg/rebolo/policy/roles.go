@@ -0,0 +1,47 @@
+package policy
+
+// Roler is implemented by users with several roles
+type Roler interface {
+	Roles() []string
+}
+
+// singleRoler is implemented by users with one role
+type singleRoler interface {
+	Role() string
+}
+
+// RolesOf returns the roles of user, which implements Roler or has a
+// Role() string method
+func RolesOf(user interface{}) []string {
+	switch u := user.(type) {
+	case Roler:
+		return u.Roles()
+	case singleRoler:
+		if role := u.Role(); role != "" {
+			return []string{role}
+		}
+	}
+	return nil
+}
+
+// HasRole reports whether user has any of roles
+func HasRole(user interface{}, roles ...string) bool {
+	for _, have := range RolesOf(user) {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Roles is a Policy granting actions by role. The "*" entry applies to
+// every action.
+// Usage: policy.Roles{"update": {"admin", "editor"}, "*": {"admin"}}
+type Roles map[string][]string
+
+// Can reports whether user has a role allowed to perform action
+func (p Roles) Can(user interface{}, action string, record interface{}) bool {
+	return HasRole(user, p[action]...) || HasRole(user, p["*"]...)
+}
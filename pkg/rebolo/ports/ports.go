@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"time"
 )
 
 // ConfigPort defines configuration operations
@@ -41,6 +42,16 @@ type ConfigData struct {
 	Server struct {
 		Port string `yaml:"port"`
 		Host string `yaml:"host"`
+
+		// Slow clients (defaults: 10s, none, 60s and 120s)
+		ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+		ReadTimeout       time.Duration `yaml:"read_timeout"` // Whole request, body included
+		WriteTimeout      time.Duration `yaml:"write_timeout"`
+		IdleTimeout       time.Duration `yaml:"idle_timeout"`
+		MaxHeaderBytes    ByteSize      `yaml:"max_header_bytes"` // Default: 1MB
+
+		MaxBodySize     ByteSize `yaml:"max_body_size"`    // Request bodies (default: 10MB, app.MaxBodySize per route)
+		MultipartMemory ByteSize `yaml:"multipart_memory"` // Kept in memory by Bind, the rest goes to temp files (default: 8MB)
 	} `yaml:"server"`
 	Database struct {
 		Driver string `yaml:"driver"` // postgres, sqlite, mysql
@@ -50,4 +61,129 @@ type ConfigData struct {
 	Assets struct {
 		HotReload bool `yaml:"hot_reload"`
 	} `yaml:"assets"`
+	Session struct {
+		Store string `yaml:"store"` // cookie (default), memory, filesystem, sql
+		Name  string `yaml:"name"`  // Cookie name (default: rebolo_session)
+		Dir   string `yaml:"dir"`   // filesystem store directory (default: tmp/sessions)
+		Table string `yaml:"table"` // sql store table (default: sessions)
+
+		Secrets  []string `yaml:"secrets"`   // First signs new cookies, the rest are accepted for rotation. SESSION_SECRET is prepended. At least 32 bytes in production.
+		Secure   *bool    `yaml:"secure"`    // HTTPS-only cookie (default: true in production)
+		Domain   string   `yaml:"domain"`    // Cookie domain
+		SameSite string   `yaml:"same_site"` // lax (default), strict, none
+		MaxAge   int      `yaml:"max_age"`   // Seconds (default: 604800, 7 days)
+	} `yaml:"session"`
+	Auth struct {
+		JWT struct {
+			Algorithm      string        `yaml:"algorithm"`        // HS256 (default) or EdDSA
+			Secret         string        `yaml:"secret"`           // HS256 secret, JWT_SECRET overrides it
+			PrivateKeyPath string        `yaml:"private_key_path"` // EdDSA PEM key
+			Issuer         string        `yaml:"issuer"`
+			Audience       string        `yaml:"audience"`
+			TTL            time.Duration `yaml:"ttl"`         // Access tokens (default: 15m)
+			RefreshTTL     time.Duration `yaml:"refresh_ttl"` // Refresh tokens (default: 168h)
+		} `yaml:"jwt"`
+		// "Sign in with ..." providers by name (app.EnableOAuth)
+		Providers map[string]struct {
+			Issuer       string   `yaml:"issuer"` // OpenID Connect discovery
+			ClientID     string   `yaml:"client_id"`
+			ClientSecret string   `yaml:"client_secret"` // OAUTH_<NAME>_CLIENT_SECRET overrides it
+			Scopes       []string `yaml:"scopes"`        // Default: openid email profile
+			RedirectURL  string   `yaml:"redirect_url"`  // Default: /auth/<name>/callback on the request host
+			AuthURL      string   `yaml:"auth_url"`      // Plain OAuth2 providers without discovery
+			TokenURL     string   `yaml:"token_url"`
+			UserInfoURL  string   `yaml:"userinfo_url"`
+			JWKSURL      string   `yaml:"jwks_url"`
+		} `yaml:"providers"`
+		// Authenticator app codes (app.EnableTwoFactor)
+		TwoFactor struct {
+			Issuer string   `yaml:"issuer"` // Shown in the authenticator app (default: app name)
+			Roles  []string `yaml:"roles"`  // Roles that must enroll (empty: every user)
+		} `yaml:"two_factor"`
+	} `yaml:"auth"`
+	// App logger and access log
+	Logging struct {
+		Format          string   `yaml:"format"`            // text (default) or json
+		Level           string   `yaml:"level"`             // debug, info (default), warn or error
+		Fields          []string `yaml:"fields"`            // Access log fields (default: method, path, status, size, duration, ip, user_agent, request_id)
+		Skip            []string `yaml:"skip"`              // Paths not logged, besides the hot reload polling
+		SampleRate      float64  `yaml:"sample_rate"`       // Fraction of successful requests logged (default: 1)
+		RequestIDHeader string   `yaml:"request_id_header"` // Default: X-Request-ID
+		IgnoreInboundID bool     `yaml:"ignore_inbound_id"` // Always generate request IDs
+	} `yaml:"logging"`
+	// app.EnableRateLimit
+	RateLimit struct {
+		Requests  int           `yaml:"requests"`  // Per window (default: 300)
+		Window    time.Duration `yaml:"window"`    // Default: 1m
+		Burst     int           `yaml:"burst"`     // token_bucket only (default: requests)
+		Algorithm string        `yaml:"algorithm"` // sliding_window (default) or token_bucket
+		Store     string        `yaml:"store"`     // memory (default) or sql, shared by all instances
+		By        string        `yaml:"by"`        // ip (default), user or api_key
+	} `yaml:"rate_limit"`
+	// app.Cache, page and fragment caching
+	Cache struct {
+		Store      string `yaml:"store"`       // memory (default), filesystem or sql, shared by all instances
+		Dir        string `yaml:"dir"`         // filesystem store directory (default: tmp/cache)
+		Table      string `yaml:"table"`       // sql store table (default: cache_entries)
+		MaxEntries int    `yaml:"max_entries"` // memory store size (default: 10000)
+	} `yaml:"cache"`
+	// Maintenance mode (rebolo maintenance on|off)
+	Maintenance struct {
+		Store         string        `yaml:"store"`          // file (default) or sql, shared by all instances
+		File          string        `yaml:"file"`           // file store flag file (default: tmp/maintenance.json)
+		AllowIPs      []string      `yaml:"allow_ips"`      // IPs or CIDR ranges always let through
+		Skip          []string      `yaml:"skip"`           // Paths served during maintenance (default: /__rebolo__/*)
+		CheckInterval time.Duration `yaml:"check_interval"` // How often the flag is read (default: 2s)
+	} `yaml:"maintenance"`
+	// app.EnableCORS
+	CORS struct {
+		AllowOrigins     []string      `yaml:"allow_origins"` // Exact, patterns (https://*.example.com) or *
+		AllowMethods     []string      `yaml:"allow_methods"`
+		AllowHeaders     []string      `yaml:"allow_headers"`
+		ExposeHeaders    []string      `yaml:"expose_headers"`
+		AllowCredentials bool          `yaml:"allow_credentials"`
+		MaxAge           time.Duration `yaml:"max_age"` // Preflight cache
+	} `yaml:"cors"`
+	// app.EnableSecureHeaders
+	SecureHeaders struct {
+		HSTSMaxAge        time.Duration       `yaml:"hsts_max_age"` // Default: 1 year in production, off otherwise
+		HSTSPreload       bool                `yaml:"hsts_preload"`
+		FrameOptions      string              `yaml:"frame_options"` // "-" leaves a header out
+		ReferrerPolicy    string              `yaml:"referrer_policy"`
+		PermissionsPolicy string              `yaml:"permissions_policy"`
+		CSP               map[string][]string `yaml:"csp"` // Sources added to the default policy per directive
+		CSPReportOnly     bool                `yaml:"csp_report_only"`
+		ReportPath        string              `yaml:"report_path"` // Default: /__rebolo__/csp-reports, "-" disables reports
+	} `yaml:"secure_headers"`
+	Mail struct {
+		From     string `yaml:"from"`     // Default sender address
+		BaseURL  string `yaml:"base_url"` // Used for absolute URLs in emails
+		Delivery string `yaml:"delivery"` // smtp, file, memory, log, sendmail (defaults per environment)
+		SMTP     struct {
+			Host               string        `yaml:"host"`
+			Port               int           `yaml:"port"`
+			Username           string        `yaml:"username"`
+			Password           string        `yaml:"password"`
+			Auth               string        `yaml:"auth"`                 // plain, login, cram-md5, none
+			TLS                string        `yaml:"tls"`                  // opportunistic, starttls, tls, none
+			InsecureSkipVerify bool          `yaml:"insecure_skip_verify"` // Development only
+			LocalName          string        `yaml:"local_name"`
+			DialTimeout        time.Duration `yaml:"dial_timeout"` // e.g. 10s
+			Timeout            time.Duration `yaml:"timeout"`      // e.g. 30s
+			KeepAlive          bool          `yaml:"keep_alive"`   // Reuse the connection for bulk sending
+		} `yaml:"smtp"`
+		File struct {
+			Dir string `yaml:"dir"` // Default: tmp/mail
+		} `yaml:"file"`
+		Sendmail struct {
+			Path string   `yaml:"path"` // Default: /usr/sbin/sendmail
+			Args []string `yaml:"args"`
+		} `yaml:"sendmail"`
+		DKIM struct {
+			Domain         string   `yaml:"domain"`
+			Selector       string   `yaml:"selector"`
+			PrivateKeyPath string   `yaml:"private_key_path"` // PEM, RSA or Ed25519
+			Headers        []string `yaml:"headers"`          // Headers to sign (default: From, To, Subject, ...)
+		} `yaml:"dkim"`
+	} `yaml:"mail"`
 }
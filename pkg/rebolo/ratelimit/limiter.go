@@ -0,0 +1,164 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Result is the outcome of a limit check
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // Until the limit is fully available again
+	RetryAfter time.Duration // Until the next request is allowed (denied requests)
+}
+
+// Limiter decides whether the client identified by key may make a request
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+	// Policy describes the limit for the RateLimit-Policy header, e.g. 100;w=60
+	Policy() string
+}
+
+// TokenBucket allows bursts of up to Burst requests, refilled at Rate
+// tokens per Per
+type TokenBucket struct {
+	store Store
+	rate  float64 // Tokens per second
+	burst int
+	per   time.Duration
+}
+
+// NewTokenBucket creates a token bucket limiter. A nil store keeps the
+// buckets in memory.
+// Usage: ratelimit.NewTokenBucket(nil, 10, time.Second, 20) // 10/s, bursts of 20
+func NewTokenBucket(store Store, rate int, per time.Duration, burst int) *TokenBucket {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	if burst < 1 {
+		burst = rate
+	}
+	return &TokenBucket{store: store, rate: float64(rate) / per.Seconds(), burst: burst, per: per}
+}
+
+func (l *TokenBucket) Allow(ctx context.Context, key string) (Result, error) {
+	result := Result{Limit: l.burst}
+	burst := float64(l.burst)
+	// Keep buckets until they would be full again
+	ttl := time.Duration(burst/l.rate*float64(time.Second)) + time.Second
+
+	err := l.store.Update(ctx, key, ttl, func(state *State) {
+		now := time.Now()
+		if state.Time.IsZero() {
+			state.Value = burst
+		} else if elapsed := now.Sub(state.Time).Seconds(); elapsed > 0 {
+			state.Value = math.Min(burst, state.Value+elapsed*l.rate)
+		}
+		state.Time = now
+
+		if state.Value >= 1 {
+			state.Value--
+			result.Allowed = true
+		} else {
+			result.RetryAfter = seconds((1 - state.Value) / l.rate)
+		}
+		result.Remaining = int(math.Floor(state.Value))
+		result.Reset = seconds((burst - state.Value) / l.rate)
+	})
+	return result, err
+}
+
+func (l *TokenBucket) Policy() string {
+	return fmt.Sprintf("%d;w=%d;burst=%d", int(math.Round(l.rate*l.per.Seconds())), int(l.per.Seconds()), l.burst)
+}
+
+// SlidingWindow allows Limit requests in any Window, estimated from the
+// counts of the current and previous fixed windows
+type SlidingWindow struct {
+	store  Store
+	limit  int
+	window time.Duration
+}
+
+// NewSlidingWindow creates a sliding window limiter. A nil store keeps the
+// counters in memory.
+// Usage: ratelimit.NewSlidingWindow(nil, 100, time.Minute)
+func NewSlidingWindow(store Store, limit int, window time.Duration) *SlidingWindow {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &SlidingWindow{store: store, limit: limit, window: window}
+}
+
+func (l *SlidingWindow) Allow(ctx context.Context, key string) (Result, error) {
+	result := Result{Limit: l.limit}
+	limit := float64(l.limit)
+
+	err := l.store.Update(ctx, key, 2*l.window, func(state *State) {
+		now := time.Now()
+		start := now.Truncate(l.window)
+
+		// Move to the current window
+		if !state.Time.Equal(start) {
+			if state.Time.Equal(start.Add(-l.window)) {
+				state.Previous = state.Value
+			} else {
+				state.Previous = 0
+			}
+			state.Value = 0
+			state.Time = start
+		}
+
+		elapsed := now.Sub(start).Seconds() / l.window.Seconds()
+		count := state.Previous*(1-elapsed) + state.Value
+
+		if count+1 <= limit {
+			state.Value++
+			count++
+			result.Allowed = true
+		} else {
+			result.RetryAfter = l.retryAfter(state, elapsed)
+		}
+		result.Remaining = int(math.Max(0, math.Floor(limit-count)))
+		result.Reset = start.Add(l.window).Sub(now)
+		if state.Value > 0 {
+			// Requests of this window still count during the next one
+			result.Reset += l.window
+		}
+	})
+	return result, err
+}
+
+// retryAfter estimates when the window will have room for one more request
+func (l *SlidingWindow) retryAfter(state *State, elapsed float64) time.Duration {
+	room := float64(l.limit) - 1
+	window := l.window.Seconds()
+
+	// Still in this window, once enough of the previous one slid out
+	if state.Value <= room && state.Previous > 0 {
+		x := 1 - (room-state.Value)/state.Previous
+		return seconds((x - elapsed) * window)
+	}
+
+	// In the next window, where this one becomes the previous
+	x := 0.0
+	if state.Value > 0 {
+		x = math.Max(0, 1-room/state.Value)
+	}
+	return seconds((1 - elapsed + x) * window)
+}
+
+func (l *SlidingWindow) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.limit, int(l.window.Seconds()))
+}
+
+func seconds(s float64) time.Duration {
+	if s < 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/auth"
+	rerrors "github.com/Palaciodiego008/rebololang/pkg/rebolo/errors"
+	"github.com/gorilla/mux"
+)
+
+// ErrLimited is reported for requests over the limit
+var ErrLimited = errors.New("rate limit exceeded")
+
+// KeyFunc identifies the client a request is counted for
+type KeyFunc func(r *http.Request) string
+
+// ByIP counts requests per client IP (the connection address; put a proxy
+// that overwrites RemoteAddr in front when running behind a load balancer)
+func ByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// ByUser counts requests per logged in user (see app.EnableAuth), or per
+// IP for anonymous requests
+func ByUser(r *http.Request) string {
+	if id := auth.UserIDFrom(r); id != "" {
+		return "user:" + id
+	}
+	return ByIP(r)
+}
+
+// ByAPIKey counts requests per API key (see app.EnableAPIKeys), falling
+// back to ByUser
+func ByAPIKey(r *http.Request) string {
+	if p := auth.PrincipalFrom(r); p != nil && p.Method == auth.MethodAPIKey {
+		return "key:" + p.KeyID
+	}
+	return ByUser(r)
+}
+
+// Config configures the rate limiting middleware
+type Config struct {
+	Limiter Limiter // Required
+	Key     KeyFunc // Default: ByIP
+
+	// Name separates the counters of limiters sharing a store (default:
+	// "global", or the route path template with PerRoute)
+	Name string
+
+	// PerRoute counts each route separately (e.g. a group limit applied to
+	// every route of the group)
+	PerRoute bool
+
+	// Router resolves the route of PerRoute limiters running before routing
+	// (app.Use). Requests matching no route share one counter.
+	Router *mux.Router
+
+	// OnLimit writes the 429 response (default: plain text error)
+	OnLimit func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// Middleware limits requests with config.Limiter, adding the
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers, and Retry-After to 429 responses. Requests
+// pass when the store fails.
+// Usage: app.Use(ratelimit.Middleware(ratelimit.Config{Limiter: ratelimit.NewSlidingWindow(nil, 100, time.Minute)}))
+func Middleware(config Config) func(http.Handler) http.Handler {
+	if config.Key == nil {
+		config.Key = ByIP
+	}
+	if config.Name == "" && !config.PerRoute {
+		config.Name = "global"
+	}
+	if config.OnLimit == nil {
+		config.OnLimit = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		}
+	}
+	policy := config.Limiter.Policy()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := config.Name
+			if config.PerRoute {
+				if name != "" {
+					name += " "
+				}
+				name += routeName(r, config.Router)
+			}
+
+			result, err := config.Limiter.Allow(r.Context(), name+"|"+config.Key(r))
+			if err != nil {
+				log.Printf("⚠️  Rate limit check failed, letting the request through: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			header.Set("RateLimit-Policy", policy)
+
+			if !result.Allowed {
+				header.Set("Retry-After", ceilSeconds(result.RetryAfter))
+				config.OnLimit(w, r, rerrors.NewHTTPError(http.StatusTooManyRequests, ErrLimited))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeName returns the method and path template of the route r matches,
+// or "*" when there is none, so made up paths don't get fresh counters
+func routeName(r *http.Request, router *mux.Router) string {
+	route := mux.CurrentRoute(r)
+	if route == nil && router != nil {
+		var match mux.RouteMatch
+		if router.Match(r, &match) && match.MatchErr == nil {
+			route = match.Route
+		}
+	}
+	if route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return r.Method + " " + template
+		}
+	}
+	return r.Method + " *"
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+)
+
+// State is what limiters keep per key
+type State struct {
+	Value    float64   // Tokens left (token bucket) or requests in the current window (sliding window)
+	Previous float64   // Requests in the previous window (sliding window)
+	Time     time.Time // Last refill (token bucket) or start of the current window (sliding window)
+}
+
+// Store keeps limiter state between requests. Update must run fn
+// atomically for key: concurrent updates of the same key are serialized,
+// also across instances for shared stores.
+type Store interface {
+	// Update passes the state of key to fn (the zero State when missing or
+	// older than ttl) and saves the changes fn made
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(state *State)) error
+}
+
+// cleanupInterval is how often stores drop expired keys
+const cleanupInterval = time.Minute
+
+// MemoryStore keeps limiter state in process memory. Limits aren't shared
+// between instances, use SQLStore for that.
+type MemoryStore struct {
+	mu          sync.Mutex
+	entries     map[string]memoryEntry
+	lastCleanup time.Time
+}
+
+type memoryEntry struct {
+	state   State
+	expires time.Time
+}
+
+// NewMemoryStore creates an in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry), lastCleanup: time.Now()}
+}
+
+func (s *MemoryStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(state *State)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastCleanup) > cleanupInterval {
+		for k, entry := range s.entries {
+			if now.After(entry.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastCleanup = now
+	}
+
+	var state State
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		state = entry.state
+	}
+	fn(&state)
+	s.entries[key] = memoryEntry{state: state, expires: now.Add(ttl)}
+	return nil
+}
+
+// SQLStore keeps limiter state in a database table so every instance of the
+// app shares the limits:
+//
+//	CREATE TABLE rate_limits (
+//		id VARCHAR(255) PRIMARY KEY,
+//		value DOUBLE PRECISION NOT NULL,
+//		previous DOUBLE PRECISION NOT NULL,
+//		stamp BIGINT NOT NULL,
+//		expires_at BIGINT NOT NULL
+//	)
+type SQLStore struct {
+	db          *sql.DB
+	table       string
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+var validTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewSQLStore creates a store using the "rate_limits" table, creating it
+// if needed
+// Usage: store, err := ratelimit.NewSQLStore(app.DB())
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	return NewSQLStoreWithTable(db, "rate_limits")
+}
+
+// NewSQLStoreWithTable creates a SQL store using table
+func NewSQLStoreWithTable(db *sql.DB, table string) (*SQLStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("sql rate limit store requires a database connection")
+	}
+	if !validTableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid rate limit table name: %s", table)
+	}
+
+	s := &SQLStore{db: db, table: table, lastCleanup: time.Now()}
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(255) PRIMARY KEY,
+	value DOUBLE PRECISION NOT NULL,
+	previous DOUBLE PRECISION NOT NULL,
+	stamp BIGINT NOT NULL,
+	expires_at BIGINT NOT NULL
+)`, table)
+	if _, err := db.Exec(query); err != nil {
+		return nil, fmt.Errorf("failed to create rate limit table: %w", err)
+	}
+	return s, nil
+}
+
+// Update locks the row of key inside a transaction (SQLite serializes
+// writers on its own)
+func (s *SQLStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(state *State)) error {
+	s.cleanup()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	dialect := adapters.Dialect(s.db)
+
+	var insert, lock string
+	switch dialect {
+	case adapters.DialectPostgres:
+		insert = "INSERT INTO %s (id, value, previous, stamp, expires_at) VALUES (?, 0, 0, 0, 0) ON CONFLICT (id) DO NOTHING"
+		lock = " FOR UPDATE"
+	case adapters.DialectMySQL:
+		insert = "INSERT IGNORE INTO %s (id, value, previous, stamp, expires_at) VALUES (?, 0, 0, 0, 0)"
+		lock = " FOR UPDATE"
+	default:
+		insert = "INSERT OR IGNORE INTO %s (id, value, previous, stamp, expires_at) VALUES (?, 0, 0, 0, 0)"
+	}
+	if _, err := tx.ExecContext(ctx, adapters.Rebind(s.db, fmt.Sprintf(insert, s.table)), key); err != nil {
+		return err
+	}
+
+	var (
+		state     State
+		stamp     int64
+		expiresAt int64
+	)
+	query := adapters.Rebind(s.db, fmt.Sprintf("SELECT value, previous, stamp, expires_at FROM %s WHERE id = ?", s.table)+lock)
+	if err := tx.QueryRowContext(ctx, query, key).Scan(&state.Value, &state.Previous, &stamp, &expiresAt); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if expiresAt <= now.Unix() {
+		state = State{}
+	} else if stamp != 0 {
+		state.Time = time.Unix(0, stamp)
+	}
+
+	fn(&state)
+
+	stamp = 0
+	if !state.Time.IsZero() {
+		stamp = state.Time.UnixNano()
+	}
+	update := adapters.Rebind(s.db, fmt.Sprintf("UPDATE %s SET value = ?, previous = ?, stamp = ?, expires_at = ? WHERE id = ?", s.table))
+	if _, err := tx.ExecContext(ctx, update, state.Value, state.Previous, stamp, now.Add(ttl).Unix()+1, key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// cleanup removes expired rows about once a minute
+func (s *SQLStore) cleanup() {
+	s.mu.Lock()
+	if time.Since(s.lastCleanup) < cleanupInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastCleanup = time.Now()
+	s.mu.Unlock()
+
+	go s.db.Exec(adapters.Rebind(s.db, fmt.Sprintf("DELETE FROM %s WHERE expires_at <= ?", s.table)), time.Now().Unix())
+}
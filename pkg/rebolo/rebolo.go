@@ -5,17 +5,25 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/auth"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/cache"
 	rebolocontext "github.com/Palaciodiego008/rebololang/pkg/rebolo/context"
 	"github.com/Palaciodiego008/rebololang/pkg/rebolo/core"
 	"github.com/Palaciodiego008/rebololang/pkg/rebolo/errors"
 	"github.com/Palaciodiego008/rebololang/pkg/rebolo/logging"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/mail"
 	"github.com/Palaciodiego008/rebololang/pkg/rebolo/middleware"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/policy"
 	"github.com/Palaciodiego008/rebololang/pkg/rebolo/ports"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/ratelimit"
 	"github.com/Palaciodiego008/rebololang/pkg/rebolo/resource"
 	"github.com/Palaciodiego008/rebololang/pkg/rebolo/routing"
 	"github.com/Palaciodiego008/rebololang/pkg/rebolo/session"
@@ -36,10 +44,26 @@ type Application struct {
 	errorHandlers   errors.ErrorHandlers        // Custom error handlers
 	middlewareStack *middleware.MiddlewareStack // Middleware stack with skip patterns
 	worker          worker.Worker               // Background worker for jobs
+	mailer          *mail.Mailer                // Template-based mailer
+	authenticator   *auth.Authenticator         // Login sessions and RequireLogin
+	apiKeys         *auth.APIKeyStore           // API keys (EnableAPIKeys)
+	jwt             *auth.JWT                   // Bearer tokens (EnableJWT)
+	oauth           *auth.OAuth                 // "Sign in with ..." logins (EnableOAuth)
+	policies        *policy.Registry            // Authorization policies per model type
+	rateLimits      ratelimit.Store             // Limiter counters (EnableRateLimit, RateLimit)
+	rateLimitsMu    sync.Mutex                  // Guards rateLimits
+	cors            *corsPolicies               // CORS policies (EnableCORS, CORS)
+	corsOnce        sync.Once                   // Installs the CORS middleware once
+	bodyLimits      *bodyLimits                 // Request body limits (server.max_body_size, MaxBodySize)
+	cache           *cache.Cache                // Values, pages and fragments (Cache, CachePage)
 	mu              sync.RWMutex                // For thread-safe template reloading
 	ctx             context.Context
 	cancelFunc      context.CancelFunc
 	lastChangeTime  time.Time // Track last file change for polling
+
+	// CSRF middleware (EnableCSRF) and the paths it skips, e.g. CSP reports
+	csrf     *middleware.MiddlewareConfig
+	csrfSkip []string
 }
 
 // ConfigAdapter adapts ports.ConfigData to core.Config
@@ -55,6 +79,16 @@ func (c *ConfigAdapter) GetDatabaseDebug() bool    { return c.data.Database.Debu
 func (c *ConfigAdapter) GetEnvironment() string    { return c.data.App.Env }
 func (c *ConfigAdapter) IsHotReload() bool         { return c.data.Assets.HotReload }
 
+func (c *ConfigAdapter) GetServerConfig() core.ServerConfig {
+	return core.ServerConfig{
+		ReadHeaderTimeout: c.data.Server.ReadHeaderTimeout,
+		ReadTimeout:       c.data.Server.ReadTimeout,
+		WriteTimeout:      c.data.Server.WriteTimeout,
+		IdleTimeout:       c.data.Server.IdleTimeout,
+		MaxHeaderBytes:    int(c.data.Server.MaxHeaderBytes),
+	}
+}
+
 // New creates a new ReboloLang application
 func New() *Application {
 	// Load configuration
@@ -99,22 +133,74 @@ func New() *Application {
 	// Create core app
 	coreApp := core.NewApp(config, router, database, renderer)
 
-	// Add default middleware
+	// Structured logs, configured in the logging section
+	logCfg := configData.Logging
+	logger := logging.NewLogger(nil, logCfg.Format, logging.ParseLevel(logCfg.Level))
+	logging.SetLogger(logger)
+
+	// Add default middleware, limiting request bodies before anything
+	// reads them
+	bodyLimits := newBodyLimits(router, int64(configData.Server.MaxBodySize))
+	coreApp.AddMiddleware(core.Middleware(bodyLimits.middleware()))
 	coreApp.AddMiddleware(middleware.MethodOverride)
-	coreApp.AddMiddleware(LoggingMiddleware)
+	coreApp.AddMiddleware(core.Middleware(middleware.RequestID(middleware.RequestIDConfig{
+		Header:        logCfg.RequestIDHeader,
+		IgnoreInbound: logCfg.IgnoreInboundID,
+	})))
+	coreApp.AddMiddleware(core.Middleware(middleware.AccessLog(middleware.AccessLogConfig{
+		Logger:     logger,
+		Fields:     logCfg.Fields,
+		Skip:       append([]string{"/__rebolo__/changes"}, logCfg.Skip...),
+		SampleRate: logCfg.SampleRate,
+	})))
 	coreApp.AddMiddleware(RecoveryMiddleware)
 
+	// Maintenance mode (rebolo maintenance on), answering with
+	// views/errors/503.html
+	var app *Application
+	coreApp.AddMiddleware(core.Middleware(newMaintenance(configData, database, func(w http.ResponseWriter, r *http.Request, err error) {
+		app.HandleError(w, r, err, http.StatusServiceUnavailable)
+	})))
+	coreApp.AddMiddleware(session.FlashMiddleware)
+
+	if size := configData.Server.MultipartMemory; size > 0 {
+		validation.MaxMultipartMemory = int64(size)
+	}
+
+	// Middleware registered with app.Use (and the Enable* features) runs
+	// next, in registration order
+	middlewareStack := middleware.NewMiddlewareStack()
+	coreApp.AddMiddleware(middlewareStack.Apply)
+
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Generate a random secret key for sessions in development
-	// In production, this should come from environment variable
-	secretKey := []byte("rebolo-secret-key-change-in-production")
-	sessionStore := session.NewCookieSessionStore("rebolo_session", secretKey)
+	// Session keys come from SESSION_SECRET or session.secrets
+	secrets := configData.Session.Secrets
+	if session.IsDefaultSecret(secrets) {
+		if configData.App.Env != "production" {
+			log.Printf("⚠️  Using the default session secret, set SESSION_SECRET (see: rebolo task secret)")
+		}
+		if len(secrets) == 0 {
+			secrets = []string{session.DefaultSecret}
+		}
+	} else if short := session.ShortSecrets(secrets); len(short) > 0 && configData.App.Env != "production" {
+		log.Printf("⚠️  Session secrets shorter than %d bytes: %s (see: rebolo task secret)", session.MinSecretLength, strings.Join(short, ", "))
+	}
+	sessionStore := newSessionStore(configData, database, session.KeyPairs(secrets...)...)
+	sessionStore.SetCookieOptions(sessionCookieOptions(configData))
+	session.SetDefaultStore(sessionStore)
+
+	// Values, pages and template fragments (cache section of config.yml)
+	appCache := newCache(configData, database)
+	cache.SetDefault(appCache)
 
 	// Create background worker
 	bgWorker := worker.NewSimpleWithContext(ctx)
 
-	app := &Application{
+	// Create mailer (templates in views/mailers, layout in views/layouts/mailer.*)
+	mailer := newMailer(configData)
+
+	app = &Application{
 		App:             coreApp,
 		config:          config,
 		router:          router,
@@ -122,8 +208,11 @@ func New() *Application {
 		renderer:        renderer,
 		sessionStore:    sessionStore,
 		errorHandlers:   errors.NewErrorHandlers(),
-		middlewareStack: middleware.NewMiddlewareStack(),
+		middlewareStack: middlewareStack,
+		bodyLimits:      bodyLimits,
 		worker:          bgWorker,
+		mailer:          mailer,
+		cache:           appCache,
 		ctx:             ctx,
 		cancelFunc:      cancel,
 	}
@@ -132,9 +221,132 @@ func New() *Application {
 	router.Router.NotFoundHandler = app.NotFoundHandler()
 	router.Router.MethodNotAllowedHandler = app.MethodNotAllowedHandler()
 
+	// Let mailer templates build absolute URLs for named routes
+	mailer.SetURLResolver(app.URLFor)
+
+	// Deliver mail.DeliverLater messages through the background worker
+	if err := mail.RegisterWorker(bgWorker, mailer); err != nil {
+		log.Printf("⚠️  Failed to register mail worker: %v", err)
+	}
+
+	// Login sessions use the application session store
+	app.authenticator = auth.New(auth.Config{GetSession: app.GetSession})
+	app.policies = policy.NewRegistry()
+
 	return app
 }
 
+// newMailer creates the application mailer from the mail config section
+func newMailer(configData ports.ConfigData) *mail.Mailer {
+	cfg := configData.Mail
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("http://%s:%s", configData.Server.Host, configData.Server.Port)
+	}
+
+	delivery := cfg.Delivery
+	if delivery == "" {
+		delivery = mail.DefaultDelivery(configData.App.Env)
+	}
+
+	sender, err := mail.NewSender(mail.Config{
+		Delivery: delivery,
+		SMTP: mail.SMTPConfig{
+			Host:               cfg.SMTP.Host,
+			Port:               cfg.SMTP.Port,
+			Username:           cfg.SMTP.Username,
+			Password:           cfg.SMTP.Password,
+			AuthMethod:         cfg.SMTP.Auth,
+			TLS:                cfg.SMTP.TLS,
+			InsecureSkipVerify: cfg.SMTP.InsecureSkipVerify,
+			LocalName:          cfg.SMTP.LocalName,
+			DialTimeout:        cfg.SMTP.DialTimeout,
+			Timeout:            cfg.SMTP.Timeout,
+			KeepAlive:          cfg.SMTP.KeepAlive,
+		},
+		FileDir:      cfg.File.Dir,
+		SendmailPath: cfg.Sendmail.Path,
+		SendmailArgs: cfg.Sendmail.Args,
+	})
+	if err != nil {
+		log.Printf("⚠️  Mail delivery disabled: %v", err)
+	} else {
+		log.Printf("📧 Mail delivery: %s", delivery)
+	}
+
+	if sender != nil && cfg.DKIM.PrivateKeyPath != "" {
+		signer, err := mail.LoadDKIMSigner(cfg.DKIM.Domain, cfg.DKIM.Selector, cfg.DKIM.PrivateKeyPath, cfg.DKIM.Headers)
+		if err != nil {
+			log.Printf("⚠️  DKIM signing disabled: %v", err)
+		} else {
+			sender = mail.NewDKIMSender(sender, signer)
+			log.Printf("🔏 DKIM signing: %s (selector %s, %s)", cfg.DKIM.Domain, cfg.DKIM.Selector, signer.Algorithm())
+		}
+	}
+
+	return mail.NewMailer(sender, cfg.From, baseURL)
+}
+
+// newSessionStore creates the session store selected in the session section
+// of config.yml, falling back to cookies when it can't be created
+func newSessionStore(configData ports.ConfigData, database adapters.DatabaseAdapter, keyPairs ...[]byte) *session.SessionStore {
+	cfg := configData.Session
+
+	name := cfg.Name
+	if name == "" {
+		name = "rebolo_session"
+	}
+
+	switch strings.ToLower(cfg.Store) {
+	case "", "cookie":
+		return session.NewCookieSessionStore(name, keyPairs...)
+	case "memory":
+		log.Printf("🔑 Session store: memory")
+		return session.NewMemorySessionStore(name, keyPairs...)
+	case "filesystem":
+		log.Printf("🔑 Session store: filesystem")
+		return session.NewFilesystemSessionStore(name, cfg.Dir, keyPairs...)
+	case "sql":
+		var db *sql.DB
+		if database != nil {
+			db, _ = database.DB().(*sql.DB)
+		}
+		table := cfg.Table
+		if table == "" {
+			table = "sessions"
+		}
+		store, err := session.NewSQLSessionStoreWithTable(db, table, name, keyPairs...)
+		if err != nil {
+			log.Printf("⚠️  SQL session store unavailable, using cookies: %v", err)
+			return session.NewCookieSessionStore(name, keyPairs...)
+		}
+		log.Printf("🔑 Session store: sql (table %s)", table)
+		return store
+	default:
+		log.Printf("⚠️  Unknown session store %q, using cookies (supported: cookie, memory, filesystem, sql)", cfg.Store)
+		return session.NewCookieSessionStore(name, keyPairs...)
+	}
+}
+
+// sessionCookieOptions reads cookie options from the session section of
+// config.yml. Cookies are Secure by default in production.
+func sessionCookieOptions(configData ports.ConfigData) session.CookieOptions {
+	cfg := configData.Session
+
+	secure := configData.App.Env == "production"
+	if cfg.Secure != nil {
+		secure = *cfg.Secure
+	}
+
+	return session.CookieOptions{
+		Secure:   secure,
+		Domain:   cfg.Domain,
+		SameSite: session.ParseSameSite(cfg.SameSite),
+		MaxAge:   cfg.MaxAge,
+	}
+}
+
 // Start starts the application
 func (a *Application) Start() error {
 	port := a.config.GetPort()
@@ -142,6 +354,13 @@ func (a *Application) Start() error {
 		port = "3000"
 	}
 
+	if a.config.GetEnvironment() == "production" && session.IsDefaultSecret(a.config.data.Session.Secrets) {
+		return fmt.Errorf("refusing to start in production with the default session secret: set SESSION_SECRET or session.secrets in config.yml (generate one with: rebolo task secret)")
+	}
+	if short := session.ShortSecrets(a.config.data.Session.Secrets); a.config.GetEnvironment() == "production" && len(short) > 0 {
+		return fmt.Errorf("refusing to start in production with session secrets shorter than %d bytes: %s (generate one with: rebolo task secret)", session.MinSecretLength, strings.Join(short, ", "))
+	}
+
 	// Start background worker
 	if a.worker != nil {
 		if err := a.worker.Start(a.ctx); err != nil {
@@ -194,35 +413,106 @@ func (a *Application) ServeStatic(prefix, dir string) {
 	a.router.PathPrefix(prefix).Handler(http.StripPrefix(prefix, fs))
 }
 
-// Resource registers a RESTful resource using the old Controller interface
+// Resource registers a RESTful resource using the old Controller interface.
+// Controllers implementing policy.Protected have their actions authorized
+// first.
 func (a *Application) Resource(path string, controller core.Controller) {
-	a.router.Resource(path, controller)
+	a.resource(a.router, path, controller)
 }
 
-// ResourceWithContext registers a RESTful resource using the new Resource interface with Context
+func (a *Application) resource(router *adapters.MuxRouter, path string, controller core.Controller) {
+	if protected, ok := controller.(policy.Protected); ok {
+		controller = &protectedController{Controller: controller, app: a, permissions: protected.Permissions()}
+	}
+	router.Resource(path, controller)
+}
+
+// ResourceWithContext registers a RESTful resource using the new Resource
+// interface with Context. Resources implementing policy.Protected have their
+// actions authorized first.
 func (a *Application) ResourceWithContext(path string, res resource.Resource) {
+	a.resourceWithContext(a.router, path, res)
+}
+
+func (a *Application) resourceWithContext(router *adapters.MuxRouter, path string, res resource.Resource) {
 	base := path
 
+	var permissions policy.Permissions
+	if protected, ok := res.(policy.Protected); ok {
+		permissions = protected.Permissions()
+	}
+
+	// authorized checks the permission declared for action before running it
+	authorized := func(action string, handler ContextHandler) http.HandlerFunc {
+		return a.ContextMiddleware(func(ctx *rebolocontext.Context) error {
+			if err := a.authorizeAction(ctx.Request, permissions, action); err != nil {
+				return err
+			}
+			return handler(ctx)
+		})
+	}
+
 	// Convert Resource methods to http.HandlerFunc using ContextMiddleware
-	a.GET(base, a.ContextMiddleware(func(ctx *rebolocontext.Context) error {
-		return res.List(ctx)
-	}))
+	router.GET(base, authorized("list", res.List))
+	router.GET(base+"/{id}", authorized("show", res.Show))
+	router.POST(base, authorized("create", res.Create))
+	router.PUT(base+"/{id}", authorized("update", res.Update))
+	router.DELETE(base+"/{id}", authorized("destroy", res.Destroy))
+}
 
-	a.GET(base+"/{id}", a.ContextMiddleware(func(ctx *rebolocontext.Context) error {
-		return res.Show(ctx)
-	}))
+// authorizeAction checks the permission declared for a controller or
+// resource action, if any
+func (a *Application) authorizeAction(r *http.Request, permissions policy.Permissions, action string) error {
+	permission, ok := permissions.Actions[action]
+	if !ok {
+		return nil
+	}
+	return a.policies.Authorize(auth.UserFrom(r), permission, permissions.Model)
+}
 
-	a.POST(base, a.ContextMiddleware(func(ctx *rebolocontext.Context) error {
-		return res.Create(ctx)
-	}))
+// protectedController authorizes each action of a core.Controller
+type protectedController struct {
+	core.Controller
+	app         *Application
+	permissions policy.Permissions
+}
 
-	a.PUT(base+"/{id}", a.ContextMiddleware(func(ctx *rebolocontext.Context) error {
-		return res.Update(ctx)
-	}))
+func (c *protectedController) guard(action string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := c.app.authorizeAction(r, c.permissions, action); err != nil {
+			c.app.HandleError(w, r, err, errors.StatusCode(err))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (c *protectedController) Index(w http.ResponseWriter, r *http.Request) {
+	c.guard("index", c.Controller.Index)(w, r)
+}
+
+func (c *protectedController) Show(w http.ResponseWriter, r *http.Request) {
+	c.guard("show", c.Controller.Show)(w, r)
+}
+
+func (c *protectedController) New(w http.ResponseWriter, r *http.Request) {
+	c.guard("new", c.Controller.New)(w, r)
+}
+
+func (c *protectedController) Create(w http.ResponseWriter, r *http.Request) {
+	c.guard("create", c.Controller.Create)(w, r)
+}
 
-	a.DELETE(base+"/{id}", a.ContextMiddleware(func(ctx *rebolocontext.Context) error {
-		return res.Destroy(ctx)
-	}))
+func (c *protectedController) Edit(w http.ResponseWriter, r *http.Request) {
+	c.guard("edit", c.Controller.Edit)(w, r)
+}
+
+func (c *protectedController) Update(w http.ResponseWriter, r *http.Request) {
+	c.guard("update", c.Controller.Update)(w, r)
+}
+
+func (c *protectedController) Delete(w http.ResponseWriter, r *http.Request) {
+	c.guard("delete", c.Controller.Delete)(w, r)
 }
 
 // createRenderer creates a new HTML renderer (used for hot reload)
@@ -287,16 +577,27 @@ func (a *Application) GetSession(r *http.Request, w http.ResponseWriter) (*sessi
 // SetSessionStore allows custom session store configuration
 func (a *Application) SetSessionStore(store *session.SessionStore) {
 	a.sessionStore = store
+	session.SetDefaultStore(store)
 }
 
 // Shutdown gracefully shuts down the application
 func (a *Application) Shutdown() {
+	// Let the requests in progress finish
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := a.StopServer(ctx); err != nil {
+		log.Printf("⚠️  Server shutdown: %v", err)
+	}
+
 	if a.watcher != nil {
 		a.watcher.Close()
 	}
 	if a.worker != nil {
 		a.worker.Stop()
 	}
+	if a.sessionStore != nil {
+		a.sessionStore.Close()
+	}
 	if a.cancelFunc != nil {
 		a.cancelFunc()
 	}
@@ -309,6 +610,14 @@ func (a *Application) RenderHTML(w http.ResponseWriter, template string, data in
 	return a.renderer.RenderHTML(w, template, data)
 }
 
+// RenderHTMLWithRequest renders a template with request-bound helpers such as
+// {{csrf_field}}
+func (a *Application) RenderHTMLWithRequest(w http.ResponseWriter, r *http.Request, template string, data interface{}) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.renderer.RenderHTMLWithRequest(w, r, template, data)
+}
+
 func (a *Application) RenderJSON(w http.ResponseWriter, data interface{}) error {
 	return a.renderer.RenderJSON(w, data)
 }
@@ -327,6 +636,12 @@ func (a *Application) DB() *sql.DB {
 	return nil
 }
 
+// Logger returns the app logger, set up in the logging section of
+// config.yml (see logging.FromContext for request loggers)
+func (a *Application) Logger() *slog.Logger {
+	return logging.Default()
+}
+
 // LogQuery logs a SQL query in yellow (helper for controllers)
 func (a *Application) LogQuery(query string, args ...interface{}) {
 	if a.config.GetDatabaseDebug() || a.config.GetEnvironment() == "development" {
@@ -339,60 +654,30 @@ func (a *Application) LogQueryError(query string, err error, args ...interface{}
 	logging.LogQueryError(query, err, args...)
 }
 
-// responseWriter wraps http.ResponseWriter to capture status code and size
-type loggingResponseWriter struct {
-	http.ResponseWriter
-	statusCode int
-	size       int
-}
-
-func newLoggingResponseWriter(w http.ResponseWriter) *loggingResponseWriter {
-	return &loggingResponseWriter{w, http.StatusOK, 0}
-}
-
-func (lrw *loggingResponseWriter) WriteHeader(code int) {
-	lrw.statusCode = code
-	lrw.ResponseWriter.WriteHeader(code)
+// LogQueryContext logs a SQL query with the request ID of ctx
+// Usage: app.LogQueryContext(r.Context(), query, id)
+func (a *Application) LogQueryContext(ctx context.Context, query string, args ...interface{}) {
+	if a.config.GetDatabaseDebug() || a.config.GetEnvironment() == "development" {
+		logging.LogQueryContext(ctx, query, args...)
+	}
 }
 
-func (lrw *loggingResponseWriter) Write(b []byte) (int, error) {
-	size, err := lrw.ResponseWriter.Write(b)
-	lrw.size += size
-	return size, err
+// LogQueryErrorContext logs a SQL query error with the request ID of ctx
+func (a *Application) LogQueryErrorContext(ctx context.Context, query string, err error, args ...interface{}) {
+	logging.LogQueryErrorContext(ctx, query, err, args...)
 }
 
-// Middleware
+// LoggingMiddleware logs requests with the default access log settings
+// (see middleware.AccessLog)
 func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip logging for hot reload polling endpoint to avoid spam
-		if r.URL.Path == "/__rebolo__/changes" {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		start := time.Now()
-		lrw := newLoggingResponseWriter(w)
-
-		next.ServeHTTP(lrw, r)
-
-		duration := time.Since(start)
-		log.Printf("[%s] %s %s %d %d %v %s",
-			r.Method,
-			r.RequestURI,
-			r.RemoteAddr,
-			lrw.statusCode,
-			lrw.size,
-			duration,
-			r.UserAgent(),
-		)
-	})
+	return middleware.AccessLog()(next)
 }
 
 func RecoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("Panic recovered: %v", err)
+				logging.FromContext(r.Context()).Error("panic recovered", "error", err, "method", r.Method, "path", r.URL.Path)
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			}
 		}()
@@ -416,21 +701,14 @@ func JSONError(w http.ResponseWriter, message string, status int) error {
 	return renderer.RenderError(w, message, status)
 }
 
-// Use adds a middleware to the global stack
+// Use adds a middleware to the global stack. It runs on every request, in
+// registration order, unless skipped.
 // Returns the MiddlewareConfig to allow chaining with Skip()
+// Usage: app.Use(myMiddleware).Skip("/health", "/assets/*")
 func (a *Application) Use(mw middleware.MiddlewareFunc) *middleware.MiddlewareConfig {
 	return a.middlewareStack.Use(mw)
 }
 
-// Group creates a middleware group for specific routes
-func (a *Application) Group(middlewares ...middleware.MiddlewareFunc) *middleware.MiddlewareGroup {
-	group := middleware.NewMiddlewareGroup(a.middlewareStack)
-	for _, mw := range middlewares {
-		group.Use(mw)
-	}
-	return group
-}
-
 // UpdateLastChangeTime updates the last change time for hot reload
 func (a *Application) UpdateLastChangeTime(t time.Time) {
 	a.mu.Lock()
@@ -443,6 +721,15 @@ func (a *Application) ReloadTemplates() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	a.renderer = a.createRenderer()
+	if a.mailer != nil {
+		a.mailer.Reload()
+	}
+	// Cached fragments may come from the old templates
+	if a.cache != nil {
+		if err := a.cache.Invalidate(cache.FragmentTag); err != nil {
+			log.Printf("⚠️  Failed to clear cached fragments: %v", err)
+		}
+	}
 }
 
 // Bind binds request data to a struct
@@ -476,7 +763,7 @@ func (a *Application) HandleError(w http.ResponseWriter, r *http.Request, err er
 	a.mu.RUnlock()
 
 	if renderer != nil {
-		renderErr := renderer.RenderHTML(w, templatePath, map[string]interface{}{
+		renderErr := renderer.RenderHTMLWithRequest(&errorStatusWriter{ResponseWriter: w, code: code}, r, templatePath, map[string]interface{}{
 			"Code":  code,
 			"Error": err,
 			"Path":  r.URL.Path,
@@ -552,6 +839,490 @@ func (a *Application) PerformIn(job worker.Job, d time.Duration) error {
 	return a.worker.PerformIn(job, d)
 }
 
+// Mail methods
+
+// Mailer returns the application mailer
+func (a *Application) Mailer() *mail.Mailer {
+	return a.mailer
+}
+
+// SetMailSender replaces the sender used to deliver emails
+func (a *Application) SetMailSender(sender mail.Sender) {
+	a.mailer.Sender = sender
+}
+
+// DeliverLater renders the named mailer and delivers it in the background worker
+func (a *Application) DeliverLater(name string, data interface{}, to ...string) error {
+	return a.mailer.DeliverLater(name, data, to...)
+}
+
+// errorStatusWriter sends the error status code with the rendered error page
+type errorStatusWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (w *errorStatusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *errorStatusWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(w.code)
+	return w.ResponseWriter.Write(b)
+}
+
+// EnableCSRF protects POST, PUT, PATCH and DELETE requests with a CSRF token
+// stored in the session. Forms include it with {{csrf_field}} and AJAX
+// requests send it in the X-CSRF-Token header. Rejected requests render
+// views/errors/403.html. API clients sending a bearer token or an API key
+// without the session cookie skip the check.
+// Usage: app.EnableCSRF().Skip("/webhooks/*")
+func (a *Application) EnableCSRF(configs ...middleware.CSRFConfig) *middleware.MiddlewareConfig {
+	var config middleware.CSRFConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+	if config.GetSession == nil {
+		config.GetSession = a.GetSession
+	}
+	if config.SessionCookie == "" {
+		config.SessionCookie = a.sessionStore.Name()
+	}
+	if config.Mode == middleware.CSRFModeCookie && !config.Secure {
+		config.Secure = a.config.GetEnvironment() == "production"
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			a.HandleError(w, r, err, http.StatusForbidden)
+		}
+	}
+
+	a.csrf = a.Use(middleware.CSRF(config)).Skip(a.csrfSkip...)
+	return a.csrf
+}
+
+// skipCSRF exempts paths posted to without a CSRF token, like the CSP
+// report endpoint, whether EnableCSRF runs before or after
+func (a *Application) skipCSRF(paths ...string) {
+	a.csrfSkip = append(a.csrfSkip, paths...)
+	if a.csrf != nil {
+		a.csrf.Skip(paths...)
+	}
+}
+
+// EnableAuth loads the logged in user on every request with findUser,
+// making it available through ctx.CurrentUser() and {{current_user}}
+// Usage: app.EnableAuth(models.FindUserByID)
+func (a *Application) EnableAuth(findUser auth.UserFinder) *middleware.MiddlewareConfig {
+	a.authenticator.SetUserFinder(findUser)
+
+	return a.Use(a.authenticator.LoadUser)
+}
+
+// Auth returns the authenticator used to log users in and out
+// Usage: app.Auth().Login(w, r, user.ID)
+func (a *Application) Auth() *auth.Authenticator {
+	return a.authenticator
+}
+
+// RequireLogin wraps a handler so only logged in users reach it. HTML
+// requests are redirected to /login and JSON requests get a 401.
+// Usage: app.GET("/account", app.RequireLogin(accountHandler))
+func (a *Application) RequireLogin(handler http.HandlerFunc) http.HandlerFunc {
+	return a.authenticator.RequireLogin(handler).ServeHTTP
+}
+
+// EnableAPIKeys authenticates API keys sent in the X-API-Key header or as
+// a bearer token. Keys are stored hashed in the api_keys table; create them
+// with app.APIKeys().Create or: rebolo task api-key:create
+// Usage: app.EnableAPIKeys()
+func (a *Application) EnableAPIKeys() (*middleware.MiddlewareConfig, error) {
+	store, err := auth.NewAPIKeyStore(a.DB())
+	if err != nil {
+		return nil, err
+	}
+	a.apiKeys = store
+
+	return a.Use(auth.APIKeyAuth(store, a.authenticator.LookupUser)), nil
+}
+
+// APIKeys returns the API key store, or nil before EnableAPIKeys
+func (a *Application) APIKeys() *auth.APIKeyStore {
+	return a.apiKeys
+}
+
+// EnableJWT authenticates signed bearer tokens. Without a config the
+// auth.jwt section of config.yml is used (secret from JWT_SECRET). Issued
+// refresh tokens are tracked in the refresh_tokens table, if there is a database.
+// Usage: app.EnableJWT()
+func (a *Application) EnableJWT(configs ...auth.JWTConfig) (*middleware.MiddlewareConfig, error) {
+	var config auth.JWTConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	} else {
+		jwtConfig := a.config.data.Auth.JWT
+		config = auth.JWTConfig{
+			Algorithm:  jwtConfig.Algorithm,
+			Secret:     []byte(jwtConfig.Secret),
+			Issuer:     jwtConfig.Issuer,
+			Audience:   jwtConfig.Audience,
+			TTL:        jwtConfig.TTL,
+			RefreshTTL: jwtConfig.RefreshTTL,
+		}
+		if jwtConfig.PrivateKeyPath != "" {
+			key, err := auth.LoadEd25519PrivateKey(jwtConfig.PrivateKeyPath)
+			if err != nil {
+				return nil, err
+			}
+			config.PrivateKey = key
+		}
+	}
+
+	// Refresh tokens are single-use, shared through the database when there's one
+	if config.RefreshTokens == nil && a.DB() != nil {
+		store, err := auth.NewSQLRefreshTokenStore(a.DB())
+		if err != nil {
+			return nil, err
+		}
+		config.RefreshTokens = store
+	}
+
+	tokens, err := auth.NewJWT(config)
+	if err != nil {
+		return nil, err
+	}
+	a.jwt = tokens
+
+	return a.Use(auth.BearerAuth(tokens, a.authenticator.LookupUser)), nil
+}
+
+// JWT returns the token signer, or nil before EnableJWT
+// Usage: pair, err := app.JWT().Issue(userID, "posts:read")
+func (a *Application) JWT() *auth.JWT {
+	return a.jwt
+}
+
+// EnableOAuth mounts "Sign in with ..." logins for the providers in the
+// auth.providers section of config.yml: GET /auth/{provider} redirects to
+// the provider and /auth/{provider}/callback logs in the user returned by
+// mapUser. More providers can be added with app.OAuth().AddProvider.
+// Usage: app.EnableOAuth(func(ctx context.Context, id *auth.OAuthIdentity) (interface{}, error) { ... })
+func (a *Application) EnableOAuth(mapUser auth.OAuthUserMapper) *auth.OAuth {
+	a.oauth = auth.NewOAuth(a.authenticator, auth.OAuthConfig{
+		MapUser: mapUser,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			code := errors.StatusCode(err)
+			if code == http.StatusInternalServerError {
+				log.Printf("❌ OAuth login failed: %v", err)
+			}
+			a.HandleError(w, r, err, code)
+		},
+	})
+
+	for name, provider := range a.config.data.Auth.Providers {
+		a.oauth.AddProvider(&auth.OAuthProvider{
+			Name:         name,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			Issuer:       provider.Issuer,
+			AuthURL:      provider.AuthURL,
+			TokenURL:     provider.TokenURL,
+			UserInfoURL:  provider.UserInfoURL,
+			JWKSURL:      provider.JWKSURL,
+			RedirectURL:  provider.RedirectURL,
+			Scopes:       provider.Scopes,
+		})
+		log.Printf("🔑 Sign in with %s enabled at /auth/%s", name, name)
+	}
+
+	a.GET("/auth/{provider}", a.oauth.LoginHandler)
+	a.GET("/auth/{provider}/callback", a.oauth.CallbackHandler)
+	return a.oauth
+}
+
+// OAuth returns the OAuth login handler, or nil before EnableOAuth
+func (a *Application) OAuth() *auth.OAuth {
+	return a.oauth
+}
+
+// EnableTwoFactor makes users of the auth.two_factor roles in config.yml
+// set up an authenticator app and enter its code after logging in. Users
+// without 2FA are sent to /2fa/setup and unverified sessions to /2fa, the
+// pages added by rebolo generate auth. Call it after EnableAuth.
+// Usage: app.EnableTwoFactor()
+func (a *Application) EnableTwoFactor(configs ...auth.TwoFactorConfig) *middleware.MiddlewareConfig {
+	var config auth.TwoFactorConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	} else {
+		config.Roles = a.config.data.Auth.TwoFactor.Roles
+	}
+
+	return a.Use(a.authenticator.RequireTwoFactor(config))
+}
+
+// NewTOTPEnrollment generates a 2FA secret for account with the issuer from
+// config.yml. Store enrollment.Secret once the user confirmed a code.
+// Usage: enrollment, err := app.NewTOTPEnrollment(user.Email)
+func (a *Application) NewTOTPEnrollment(account string) (*auth.TOTPEnrollment, error) {
+	issuer := a.config.data.Auth.TwoFactor.Issuer
+	if issuer == "" {
+		issuer = a.config.data.App.Name
+	}
+	return auth.NewTOTPEnrollment(issuer, account)
+}
+
+// EnableCompression compresses responses with gzip or deflate for clients
+// that accept it (see middleware.Compress)
+// Usage: app.EnableCompression().Skip("/events")
+func (a *Application) EnableCompression(configs ...middleware.CompressConfig) *middleware.MiddlewareConfig {
+	return a.Use(middleware.Compress(configs...))
+}
+
+// EnableETags tags HTML and JSON responses with a hash of their body, so
+// unchanged pages get a 304 instead of being sent again (see
+// middleware.ETag). Handlers knowing the version of their data can use
+// ctx.Fresh to skip rendering too.
+// Usage: app.EnableETags().Skip("/admin/*")
+func (a *Application) EnableETags(configs ...middleware.ETagConfig) *middleware.MiddlewareConfig {
+	return a.Use(middleware.ETag(configs...))
+}
+
+// EnableSecureHeaders sets the security headers of middleware.SecureHeaders
+// with the secure_headers section of config.yml, unless a config is given:
+// HSTS in production and a Content Security Policy (middleware.DefaultCSP
+// plus the configured sources) with a nonce per request. The violations
+// posted to the report path of the CSP are logged.
+// Usage: app.EnableSecureHeaders().Skip("/embed/*")
+func (a *Application) EnableSecureHeaders(configs ...middleware.SecureHeadersConfig) *middleware.MiddlewareConfig {
+	var config middleware.SecureHeadersConfig
+	if len(configs) > 0 {
+		config = configs[0]
+	} else {
+		cfg := a.config.data.SecureHeaders
+		config = middleware.SecureHeadersConfig{
+			HSTSMaxAge:        cfg.HSTSMaxAge,
+			HSTSPreload:       cfg.HSTSPreload,
+			FrameOptions:      cfg.FrameOptions,
+			ReferrerPolicy:    cfg.ReferrerPolicy,
+			PermissionsPolicy: cfg.PermissionsPolicy,
+			CSP:               middleware.DefaultCSP(),
+			CSPReportOnly:     cfg.CSPReportOnly,
+		}
+		if config.HSTSMaxAge == 0 && a.config.GetEnvironment() == "production" {
+			config.HSTSMaxAge = 365 * 24 * time.Hour
+			config.HSTSIncludeSubdomains = true
+		}
+		directives := make([]string, 0, len(cfg.CSP))
+		for directive := range cfg.CSP {
+			directives = append(directives, directive)
+		}
+		sort.Strings(directives)
+		for _, directive := range directives {
+			config.CSP.Add(directive, cfg.CSP[directive]...)
+		}
+
+		reportPath := cfg.ReportPath
+		if reportPath == "" {
+			reportPath = "/__rebolo__/csp-reports"
+		}
+		if reportPath != "-" {
+			config.CSP.ReportTo(reportPath)
+		}
+	}
+
+	if config.CSP != nil {
+		if path := config.CSP.ReportPath(); strings.HasPrefix(path, "/") {
+			// Browsers send reports without a CSRF token
+			a.POST(path, middleware.CSPReportHandler(config.OnReport))
+			a.skipCSRF(path)
+		}
+	}
+	return a.Use(middleware.SecureHeaders(config))
+}
+
+// EnableRateLimit limits requests per client with the rate_limit section of
+// config.yml (default: 300 requests per minute per IP, counted in memory).
+// Requests over the limit get a 429 rendered through HandleError. Call it
+// after EnableAuth or EnableAPIKeys to count per user or API key.
+// Usage: app.EnableRateLimit()
+func (a *Application) EnableRateLimit(configs ...ratelimit.Config) (*middleware.MiddlewareConfig, error) {
+	var config ratelimit.Config
+	if len(configs) > 0 {
+		config = configs[0]
+	} else {
+		store, err := a.rateLimitStore()
+		if err != nil {
+			return nil, err
+		}
+
+		cfg := a.config.data.RateLimit
+		requests, window := cfg.Requests, cfg.Window
+		if requests <= 0 {
+			requests = 300
+		}
+		if window <= 0 {
+			window = time.Minute
+		}
+
+		config.Key = rateLimitKey(cfg.By)
+		switch cfg.Algorithm {
+		case "token_bucket":
+			config.Limiter = ratelimit.NewTokenBucket(store, requests, window, cfg.Burst)
+		case "", "sliding_window":
+			config.Limiter = ratelimit.NewSlidingWindow(store, requests, window)
+		default:
+			return nil, fmt.Errorf("unknown rate limit algorithm: %s", cfg.Algorithm)
+		}
+	}
+	if config.OnLimit == nil {
+		config.OnLimit = a.rateLimited
+	}
+	// The middleware runs before routing, PerRoute needs the router
+	if config.PerRoute && config.Router == nil {
+		config.Router = a.router.Router
+	}
+	return a.Use(ratelimit.Middleware(config)), nil
+}
+
+// RateLimit wraps a handler so each client may call it at most limit times
+// per window, counted apart from other routes (e.g. login forms)
+// Usage: app.POST("/login", app.RateLimit(c.Login, 5, time.Minute))
+func (a *Application) RateLimit(handler http.HandlerFunc, limit int, window time.Duration) http.HandlerFunc {
+	store, err := a.rateLimitStore()
+	if err != nil {
+		log.Printf("⚠️  Rate limit store unavailable, counting in memory: %v", err)
+		store = ratelimit.NewMemoryStore()
+	}
+
+	return ratelimit.Middleware(ratelimit.Config{
+		Limiter:  ratelimit.NewSlidingWindow(store, limit, window),
+		Key:      rateLimitKey(a.config.data.RateLimit.By),
+		PerRoute: true,
+		OnLimit:  a.rateLimited,
+	})(handler).ServeHTTP
+}
+
+// rateLimitStore returns the store configured in rate_limit.store, shared
+// by every limiter of the app
+func (a *Application) rateLimitStore() (ratelimit.Store, error) {
+	a.rateLimitsMu.Lock()
+	defer a.rateLimitsMu.Unlock()
+
+	if a.rateLimits != nil {
+		return a.rateLimits, nil
+	}
+
+	switch store := a.config.data.RateLimit.Store; store {
+	case "sql":
+		sqlStore, err := ratelimit.NewSQLStore(a.DB())
+		if err != nil {
+			return nil, err
+		}
+		a.rateLimits = sqlStore
+	case "", "memory":
+		a.rateLimits = ratelimit.NewMemoryStore()
+	default:
+		return nil, fmt.Errorf("unknown rate limit store: %s", store)
+	}
+	return a.rateLimits, nil
+}
+
+// rateLimited renders the 429 page
+func (a *Application) rateLimited(w http.ResponseWriter, r *http.Request, err error) {
+	a.HandleError(w, r, err, http.StatusTooManyRequests)
+}
+
+// Timeout wraps a handler so its request context is canceled after d. If
+// it didn't answer by then, the client gets the 503 error page; handlers
+// returning the context error (e.g. from a query) get a 504.
+// Usage: app.GET("/reports", app.Timeout(reportsHandler, 5*time.Second))
+func (a *Application) Timeout(handler http.HandlerFunc, d time.Duration) http.HandlerFunc {
+	return middleware.Timeout(middleware.TimeoutConfig{
+		Timeout:   d,
+		OnTimeout: a.timedOut,
+	})(handler).ServeHTTP
+}
+
+func (a *Application) timedOut(w http.ResponseWriter, r *http.Request, err error) {
+	a.HandleError(w, r, err, errors.StatusCode(err))
+}
+
+// rateLimitKey returns the KeyFunc for the rate_limit.by setting
+func rateLimitKey(by string) ratelimit.KeyFunc {
+	switch by {
+	case "user":
+		return ratelimit.ByUser
+	case "api_key":
+		return ratelimit.ByAPIKey
+	default:
+		return ratelimit.ByIP
+	}
+}
+
+// RequireScope wraps a handler so only API keys and tokens granted all of
+// scopes reach it (logged in users always pass)
+// Usage: app.GET("/api/posts", app.RequireScope(listPosts, "posts:read"))
+func (a *Application) RequireScope(handler http.HandlerFunc, scopes ...string) http.HandlerFunc {
+	return auth.RequireScope(scopes...)(handler).ServeHTTP
+}
+
+// RegisterPolicy sets the policy deciding what users may do with records
+// of model's type
+// Usage: app.RegisterPolicy(&models.Post{}, PostPolicy{})
+func (a *Application) RegisterPolicy(model interface{}, p policy.Policy) {
+	a.policies.Register(model, p)
+}
+
+// Policies returns the policy registry
+func (a *Application) Policies() *policy.Registry {
+	return a.policies
+}
+
+// Authorize checks that the current user may perform action on record.
+// The returned error renders a 403 through HandleError when returned from
+// a context handler.
+// Usage: if err := app.Authorize(ctx, "update", post); err != nil { return err }
+func (a *Application) Authorize(ctx *rebolocontext.Context, action string, record interface{}) error {
+	return a.policies.Authorize(ctx.CurrentUser(), action, record)
+}
+
+// Can reports whether the current user may perform action on record
+// Usage: if app.Can(ctx, "delete", post) { ... }
+func (a *Application) Can(ctx *rebolocontext.Context, action string, record interface{}) bool {
+	return a.policies.Can(ctx.CurrentUser(), action, record)
+}
+
+// EnableMailPreview captures sent emails and serves the development mail
+// preview UI at /__rebolo__/mail. Messages are still forwarded to the
+// configured sender.
+func (a *Application) EnableMailPreview() error {
+	if a.config.GetEnvironment() == "production" {
+		return fmt.Errorf("mail preview is not available in production")
+	}
+
+	capture, ok := a.mailer.Sender.(*mail.CaptureSender)
+	if !ok {
+		capture = mail.NewCaptureSender(a.mailer.Sender, 100)
+		a.mailer.Sender = capture
+	}
+
+	prefix := "/__rebolo__/mail"
+	a.router.PathPrefix(prefix).Handler(mail.NewPreviewHandler(capture, prefix))
+
+	log.Printf("📧 Mail preview enabled at %s", prefix)
+	return nil
+}
+
+// Deliver renders the named mailer from views/mailers and sends it
+func (a *Application) Deliver(name string, data interface{}, to ...string) error {
+	return a.mailer.Deliver(name, data, to...)
+}
+
 // URLFor generates a URL for a named route with the given parameters
 func (a *Application) URLFor(name string, params map[string]string) (string, error) {
 	return routing.URLFor(a.router.Router, name, params)
@@ -1,32 +1,112 @@
 package session
 
 import (
-	"fmt"
+	"bytes"
+	"context"
+	"encoding/gob"
 	"html/template"
+	"net/http"
+	"sync"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/logging"
+	"github.com/gorilla/sessions"
 )
 
+// flashKey is the session key holding the messages for the next request
+// (Session.AddFlash uses gorilla's own "_flash" key)
+const flashKey = "_flash_messages"
+
+// FlashPartial is the template rendered by {{flashes}}. Add
+// views/shared/_flashes.html to change the markup; it receives the
+// []FlashMessage of the request.
+const FlashPartial = "shared/_flashes.html"
+
+const defaultFlashPartial = `{{range .}}<div class="alert alert-{{.Class}}" role="alert">{{.Message}}</div>{{end}}`
+
 // FlashMessage represents a flash message with a type and content
 type FlashMessage struct {
 	Type    string // success, error, warning, info
 	Message string
 }
 
-// Flash provides convenient methods for flash messages
+// Class returns the Bootstrap-compatible alert class of the message type
+func (m FlashMessage) Class() string {
+	return getAlertClass(m.Type)
+}
+
+func init() {
+	// Session values are gob encoded by every store
+	gob.Register(FlashMessage{})
+	gob.Register([]FlashMessage{})
+
+	// {{flashes}} renders the messages of the current request
+	adapters.RegisterTemplatePartial("flashes", FlashPartial, defaultFlashPartial, func(r *http.Request) interface{} {
+		if r == nil {
+			return nil
+		}
+		return GetFlash(r, nil).Get()
+	})
+}
+
+// Flash provides convenient methods for flash messages. Messages added with
+// Add (or Success, Error...) are shown on the next request, messages added
+// with Now only on the current one.
 type Flash struct {
 	session *Session
+	state   *flashState
+}
+
+// flashState holds the flash of one session during a request
+type flashState struct {
+	loaded  bool
+	current []FlashMessage // Shown in this request
+	dirty   bool           // The session needs saving
 }
 
 // NewFlash creates a new Flash instance
 func NewFlash(session *Session) *Flash {
-	return &Flash{session: session}
+	f := &Flash{session: session}
+	if session != nil && session.session != nil {
+		if tracker := flashTrackerFrom(session.r); tracker != nil {
+			f.state = tracker.state(session)
+		}
+	}
+	if f.state == nil {
+		f.state = &flashState{}
+	}
+	return f
 }
 
-// Add adds a flash message with the specified type
+// Add adds a flash message with the specified type for the next request
 func (f *Flash) Add(msgType, message string) {
-	f.session.AddFlash(FlashMessage{
-		Type:    msgType,
-		Message: message,
-	})
+	if !f.valid() {
+		return
+	}
+	f.setNext(append(f.next(), FlashMessage{Type: msgType, Message: message}))
+}
+
+// Now adds a flash message shown only in the current request (e.g. when
+// rendering a form again instead of redirecting)
+func (f *Flash) Now(msgType, message string) {
+	if !f.valid() {
+		return
+	}
+	f.load()
+	f.state.current = append(f.state.current, FlashMessage{Type: msgType, Message: message})
+}
+
+// Keep carries the messages of the current request over to the next one
+// (e.g. when redirecting again)
+func (f *Flash) Keep() {
+	if !f.valid() {
+		return
+	}
+	f.load()
+	if len(f.state.current) == 0 {
+		return
+	}
+	f.setNext(append(append([]FlashMessage{}, f.state.current...), f.next()...))
 }
 
 // Success adds a success flash message
@@ -49,18 +129,14 @@ func (f *Flash) Info(message string) {
 	f.Add("info", message)
 }
 
-// Get retrieves all flash messages and clears them
+// Get retrieves the flash messages of the current request. Messages from
+// the previous request are removed from the session the first time.
 func (f *Flash) Get() []FlashMessage {
-	flashes := f.session.Flashes()
-	var messages []FlashMessage
-
-	for _, flash := range flashes {
-		if msg, ok := flash.(FlashMessage); ok {
-			messages = append(messages, msg)
-		}
+	if !f.valid() {
+		return nil
 	}
-
-	return messages
+	f.load()
+	return f.state.current
 }
 
 // GetByType retrieves flash messages of a specific type
@@ -84,13 +160,148 @@ func (f *Flash) HTML() template.HTML {
 		return ""
 	}
 
-	html := ""
-	for _, msg := range messages {
-		alertClass := getAlertClass(msg.Type)
-		html += fmt.Sprintf(`<div class="alert alert-%s" role="alert">%s</div>`, alertClass, msg.Message)
+	var buf bytes.Buffer
+	if err := flashTemplate.Execute(&buf, messages); err != nil {
+		return ""
+	}
+	return template.HTML(buf.String())
+}
+
+var flashTemplate = template.Must(template.New("flashes").Parse(defaultFlashPartial))
+
+// valid reports whether the flash has a session to work with
+func (f *Flash) valid() bool {
+	return f.session != nil && f.session.session != nil
+}
+
+// load moves the messages of the previous request into the current one
+func (f *Flash) load() {
+	if f.state.loaded {
+		return
+	}
+	f.state.loaded = true
+
+	previous := f.next()
+	if len(previous) == 0 {
+		return
 	}
+	f.state.current = append(previous, f.state.current...)
+	f.setNext(nil)
+}
+
+// next returns the messages stored for the next request
+func (f *Flash) next() []FlashMessage {
+	messages, _ := f.session.session.Values[flashKey].([]FlashMessage)
+	return messages
+}
+
+func (f *Flash) setNext(messages []FlashMessage) {
+	if len(messages) == 0 {
+		delete(f.session.session.Values, flashKey)
+	} else {
+		f.session.session.Values[flashKey] = messages
+	}
+	f.state.dirty = true
+}
+
+type flashTrackerKey struct{}
+
+// flashTracker keeps the flash state of the sessions used in a request so
+// FlashMiddleware can save them
+type flashTracker struct {
+	mu     sync.Mutex
+	states map[*sessions.Session]*flashState
+	order  []*Session
+	saved  bool
+}
+
+func flashTrackerFrom(r *http.Request) *flashTracker {
+	if r == nil {
+		return nil
+	}
+	tracker, _ := r.Context().Value(flashTrackerKey{}).(*flashTracker)
+	return tracker
+}
+
+func (t *flashTracker) state(s *Session) *flashState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.states[s.session]
+	if !ok {
+		state = &flashState{}
+		t.states[s.session] = state
+		t.order = append(t.order, s)
+	}
+	return state
+}
+
+// save persists the sessions whose flash changed, once per request
+func (t *flashTracker) save(r *http.Request, w http.ResponseWriter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.saved {
+		return
+	}
+	t.saved = true
+
+	for _, s := range t.order {
+		if t.states[s.session].dirty {
+			if err := s.session.Save(r, w); err != nil {
+				logging.FromContext(r.Context()).Error("failed to save flash messages", "error", err, "path", r.URL.Path)
+			}
+		}
+	}
+}
+
+// FlashMiddleware saves the session automatically when flash messages
+// were added or shown, before the response headers are sent. It also
+// shares one session per request between controllers and {{flashes}}, so
+// Now messages reach the template. rebolo.New() installs it.
+func FlashMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Sessions are cached per request in the registry, create it here so
+		// every handler below sees the same one
+		sessions.GetRegistry(r)
+
+		tracker := &flashTracker{states: make(map[*sessions.Session]*flashState)}
+		r = r.WithContext(context.WithValue(r.Context(), flashTrackerKey{}, tracker))
+
+		fw := &flashResponseWriter{ResponseWriter: w, r: r, tracker: tracker}
+		next.ServeHTTP(fw, r)
+		tracker.save(r, w)
+	})
+}
+
+// flashResponseWriter saves the flash before the headers are written
+type flashResponseWriter struct {
+	http.ResponseWriter
+	r       *http.Request
+	tracker *flashTracker
+}
+
+func (w *flashResponseWriter) WriteHeader(code int) {
+	w.tracker.save(w.r, w.ResponseWriter)
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *flashResponseWriter) Write(b []byte) (int, error) {
+	w.tracker.save(w.r, w.ResponseWriter)
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush supports streaming responses
+func (w *flashResponseWriter) Flush() {
+	w.tracker.save(w.r, w.ResponseWriter)
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
 
-	return template.HTML(html)
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *flashResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
 
 // getAlertClass returns Bootstrap-compatible alert classes
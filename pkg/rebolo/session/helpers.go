@@ -2,16 +2,44 @@ package session
 
 import (
 	"net/http"
+	"sync"
 )
 
+var (
+	defaultStore   *SessionStore
+	defaultStoreMu sync.RWMutex
+)
+
+// SetDefaultStore sets the store used by GetSession and GetFlash.
+// rebolo.New() sets it to the application's session store.
+func SetDefaultStore(store *SessionStore) {
+	defaultStoreMu.Lock()
+	defer defaultStoreMu.Unlock()
+	defaultStore = store
+}
+
+// DefaultStore returns the store used by GetSession, creating a cookie store
+// with DefaultSecret when none was set
+func DefaultStore() *SessionStore {
+	defaultStoreMu.RLock()
+	store := defaultStore
+	defaultStoreMu.RUnlock()
+	if store != nil {
+		return store
+	}
+
+	defaultStoreMu.Lock()
+	defer defaultStoreMu.Unlock()
+	if defaultStore == nil {
+		defaultStore = NewCookieSessionStore("rebolo_session", KeyPairs(DefaultSecret)...)
+	}
+	return defaultStore
+}
+
 // GetSession is a convenience function to get session from request context
 // Usage in controllers: session, _ := rebolo.GetSession(r, w)
 func GetSession(r *http.Request, w http.ResponseWriter) (*Session, error) {
-	// Get the application instance from context (if available)
-	// For now, we'll create a default session store
-	// This can be improved by storing app in context
-	store := NewCookieSessionStore("rebolo_session", []byte("rebolo-secret-key-change-in-production"))
-	return store.Get(r, w)
+	return DefaultStore().Get(r, w)
 }
 
 // GetFlash is a convenience function to get flash messages
@@ -0,0 +1,133 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// DefaultSecret is the development fallback secret. Applications refuse to
+// start in production while it's in use.
+const DefaultSecret = "rebolo-secret-key-change-in-production"
+
+// MinSecretLength is the shortest secret accepted in production, in bytes
+const MinSecretLength = 32
+
+// KeyPairs derives a hash key and an encryption key from each secret.
+// The first secret signs new cookies and the rest are only accepted when
+// decoding, so a secret can be rotated by prepending a new one.
+// Usage: session.NewCookieSessionStore("rebolo_session", session.KeyPairs(newSecret, oldSecret)...)
+func KeyPairs(secrets ...string) [][]byte {
+	pairs := make([][]byte, 0, len(secrets)*2)
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		pairs = append(pairs,
+			deriveKey(secret, "rebolo session hash key"),
+			deriveKey(secret, "rebolo session encryption key"),
+		)
+	}
+	return pairs
+}
+
+// IsDefaultSecret reports whether the current secret is missing or DefaultSecret
+func IsDefaultSecret(secrets []string) bool {
+	return len(secrets) == 0 || secrets[0] == "" || secrets[0] == DefaultSecret
+}
+
+// ShortSecrets returns the secrets shorter than MinSecretLength, rotated
+// ones included
+func ShortSecrets(secrets []string) []string {
+	var short []string
+	for i, secret := range secrets {
+		if secret != "" && len(secret) < MinSecretLength {
+			short = append(short, fmt.Sprintf("#%d (%d bytes)", i+1, len(secret)))
+		}
+	}
+	return short
+}
+
+func deriveKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// CookieOptions configures the session cookie
+type CookieOptions struct {
+	Secure   bool
+	Domain   string
+	SameSite http.SameSite
+	MaxAge   int // Seconds, 0 keeps the default (7 days)
+}
+
+// ParseSameSite converts lax, strict or none to http.SameSite (default: lax)
+func ParseSameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// SetCookieOptions applies cookie options to every session created afterwards
+func (ss *SessionStore) SetCookieOptions(opts CookieOptions) {
+	options := ss.Options()
+	if options == nil {
+		return
+	}
+
+	options.Secure = opts.Secure
+	options.Domain = opts.Domain
+	if opts.SameSite != 0 {
+		options.SameSite = opts.SameSite
+	}
+	if opts.SameSite == http.SameSiteNoneMode {
+		// Browsers reject SameSite=None cookies that aren't Secure
+		options.Secure = true
+	}
+	if opts.MaxAge > 0 {
+		options.MaxAge = opts.MaxAge
+		ss.setCodecMaxAge(opts.MaxAge)
+	}
+}
+
+// Options returns the cookie options of the underlying store
+func (ss *SessionStore) Options() *sessions.Options {
+	switch s := ss.store.(type) {
+	case *sessions.CookieStore:
+		return s.Options
+	case *serverStore:
+		return s.Options
+	}
+	return nil
+}
+
+// Name returns the session cookie name
+func (ss *SessionStore) Name() string {
+	return ss.name
+}
+
+func (ss *SessionStore) setCodecMaxAge(age int) {
+	var codecs []securecookie.Codec
+	switch s := ss.store.(type) {
+	case *sessions.CookieStore:
+		codecs = s.Codecs
+	case *serverStore:
+		codecs = s.Codecs
+	}
+	for _, codec := range codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(age)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+const rotatedSecret = "fedcba9876543210fedcba9876543210"
+
+func TestKeyRotation(t *testing.T) {
+	for name, open := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			oldStore := open(t, KeyPairs(testSecret)...)
+			cookies := save(t, oldStore, map[string]interface{}{"user_id": "42"})
+
+			tests := []struct {
+				name    string
+				secrets []string
+				want    string
+			}{
+				{"new secret first", []string{rotatedSecret, testSecret}, "42"},
+				{"old secret dropped", []string{rotatedSecret}, ""},
+			}
+			for _, tt := range tests {
+				store := open(t, KeyPairs(tt.secrets...)...)
+				request(t, store, cookies, func(sess *Session) {
+					if got := sess.GetString("user_id"); got != tt.want {
+						t.Errorf("%s: user_id = %q, want %q", tt.name, got, tt.want)
+					}
+				})
+			}
+
+			// Cookies re-saved after rotating are signed with the new secret only
+			rotated := open(t, KeyPairs(rotatedSecret, testSecret)...)
+			resaved := request(t, rotated, cookies, func(sess *Session) {
+				if err := sess.Save(); err != nil {
+					t.Fatal(err)
+				}
+			})
+			request(t, open(t, KeyPairs(rotatedSecret)...), resaved, func(sess *Session) {
+				if got := sess.GetString("user_id"); got != "42" {
+					t.Errorf("re-saved cookie: user_id = %q, want 42", got)
+				}
+			})
+		})
+	}
+}
+
+func TestKeyPairs(t *testing.T) {
+	pairs := KeyPairs(testSecret, "", rotatedSecret)
+	if len(pairs) != 4 {
+		t.Fatalf("KeyPairs() returned %d keys, want 4 (empty secrets skipped)", len(pairs))
+	}
+	if reflect.DeepEqual(pairs[0], pairs[1]) || reflect.DeepEqual(pairs[0], pairs[2]) {
+		t.Error("KeyPairs() derived the same key twice")
+	}
+	if !reflect.DeepEqual(KeyPairs(testSecret), pairs[:2]) {
+		t.Error("KeyPairs() isn't deterministic")
+	}
+}
+
+func TestSecretChecks(t *testing.T) {
+	tests := []struct {
+		name        string
+		secrets     []string
+		wantDefault bool
+		wantShort   []string
+	}{
+		{"none", nil, true, nil},
+		{"empty", []string{""}, true, nil},
+		{"default", []string{DefaultSecret}, true, nil},
+		{"strong", []string{testSecret}, false, nil},
+		{"short", []string{"secret"}, false, []string{"#1 (6 bytes)"}},
+		{"short rotated secret", []string{testSecret, "old"}, false, []string{"#2 (3 bytes)"}},
+	}
+	for _, tt := range tests {
+		if got := IsDefaultSecret(tt.secrets); got != tt.wantDefault {
+			t.Errorf("%s: IsDefaultSecret() = %v, want %v", tt.name, got, tt.wantDefault)
+		}
+		if got := ShortSecrets(tt.secrets); !reflect.DeepEqual(got, tt.wantShort) {
+			t.Errorf("%s: ShortSecrets() = %v, want %v", tt.name, got, tt.wantShort)
+		}
+	}
+}
+
+func TestSetCookieOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         CookieOptions
+		wantSecure   bool
+		wantSameSite http.SameSite
+		wantMaxAge   int
+	}{
+		{"defaults", CookieOptions{}, false, http.SameSiteLaxMode, 86400 * 7},
+		{"secure strict", CookieOptions{Secure: true, SameSite: ParseSameSite("strict"), MaxAge: 3600}, true, http.SameSiteStrictMode, 3600},
+		{"none forces secure", CookieOptions{SameSite: ParseSameSite("None")}, true, http.SameSiteNoneMode, 86400 * 7},
+		{"unknown same site", CookieOptions{SameSite: ParseSameSite("bogus")}, false, http.SameSiteLaxMode, 86400 * 7},
+	}
+	for name, open := range testStores(t) {
+		for _, tt := range tests {
+			store := open(t, KeyPairs(testSecret)...)
+			store.SetCookieOptions(tt.opts)
+			cookies := save(t, store, map[string]interface{}{"user_id": "42"})
+
+			if len(cookies) != 1 {
+				t.Fatalf("%s/%s: got %d cookies, want 1", name, tt.name, len(cookies))
+			}
+			cookie := cookies[0]
+			if cookie.Secure != tt.wantSecure || cookie.SameSite != tt.wantSameSite || cookie.MaxAge != tt.wantMaxAge || !cookie.HttpOnly {
+				t.Errorf("%s/%s: cookie Secure=%v SameSite=%v MaxAge=%d HttpOnly=%v, want Secure=%v SameSite=%v MaxAge=%d HttpOnly",
+					name, tt.name, cookie.Secure, cookie.SameSite, cookie.MaxAge, cookie.HttpOnly, tt.wantSecure, tt.wantSameSite, tt.wantMaxAge)
+			}
+		}
+	}
+}
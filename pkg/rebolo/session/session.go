@@ -1,8 +1,10 @@
 package session
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 )
 
@@ -30,6 +32,14 @@ func NewCookieSessionStore(name string, keyPairs ...[]byte) *SessionStore {
 	}
 }
 
+// Close stops the background cleanup of server-side stores
+func (ss *SessionStore) Close() error {
+	if s, ok := ss.store.(*serverStore); ok {
+		s.close()
+	}
+	return nil
+}
+
 // Session represents a user session
 type Session struct {
 	session *sessions.Session
@@ -41,7 +51,13 @@ type Session struct {
 func (ss *SessionStore) Get(r *http.Request, w http.ResponseWriter) (*Session, error) {
 	session, err := ss.store.Get(r, ss.name)
 	if err != nil {
-		return nil, err
+		// Cookies that no longer decode (rotated keys, tampering) start
+		// over with the new session the store returns, which replaces the
+		// cookie when saved
+		var cookieErr securecookie.Error
+		if session == nil || !errors.As(err, &cookieErr) || !cookieErr.IsDecode() {
+			return nil, err
+		}
 	}
 
 	return &Session{
@@ -111,6 +127,19 @@ func (s *Session) Destroy() error {
 	return s.session.Save(s.r, s.w)
 }
 
+// Regenerate moves the session data to a new ID and invalidates the old one.
+// Call it on login to prevent session fixation.
+func (s *Session) Regenerate() error {
+	if store, ok := s.session.Store().(*serverStore); ok {
+		if err := store.remove(s.session.ID); err != nil {
+			return err
+		}
+	}
+	s.session.ID = ""
+	s.session.IsNew = true
+	return s.Save()
+}
+
 // AddFlash adds a flash message to the session
 func (s *Session) AddFlash(value interface{}, vars ...string) {
 	s.session.AddFlash(value, vars...)
@@ -0,0 +1,176 @@
+package session
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/gob"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// backend persists encoded session data by ID for server-side stores
+type backend interface {
+	load(id string) ([]byte, bool, error)
+	save(id string, data []byte, expires time.Time) error
+	delete(id string) error
+	cleanup(now time.Time) error
+}
+
+// serverStore implements sessions.Store keeping values on the server and
+// only a signed session ID in the cookie
+type serverStore struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+	backend backend
+	done    chan struct{}
+	once    sync.Once
+}
+
+// DefaultCleanupInterval is how often server-side stores remove expired sessions
+var DefaultCleanupInterval = 5 * time.Minute
+
+func newServerStore(b backend, keyPairs ...[]byte) *serverStore {
+	s := &serverStore{
+		Codecs:  securecookie.CodecsFromPairs(keyPairs...),
+		Options: defaultOptions(),
+		backend: b,
+		done:    make(chan struct{}),
+	}
+	for _, codec := range s.Codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(s.Options.MaxAge)
+		}
+	}
+
+	go s.cleanupLoop(DefaultCleanupInterval)
+	return s
+}
+
+// defaultOptions are the cookie options shared by every store
+func defaultOptions() *sessions.Options {
+	return &sessions.Options{
+		Path:     "/",
+		MaxAge:   86400 * 7, // 7 days
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// Get returns the session cached for the request or loads it
+func (s *serverStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session referenced by the request cookie, or returns a new one
+func (s *serverStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var id string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &id, s.Codecs...); err != nil {
+		// Invalid or tampered cookie, start over with a new session
+		return session, nil
+	}
+
+	data, found, err := s.backend.load(id)
+	if err != nil {
+		return session, err
+	}
+	if !found {
+		return session, nil
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values); err != nil {
+		return session, err
+	}
+	session.ID = id
+	session.IsNew = false
+	return session, nil
+}
+
+// Save persists the session values and writes the ID cookie. A negative
+// MaxAge deletes the session.
+func (s *serverStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.backend.delete(session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = generateSessionID()
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(session.Values); err != nil {
+		return err
+	}
+
+	maxAge := session.Options.MaxAge
+	if maxAge == 0 {
+		// Browser-session cookie, keep the data for the default lifetime
+		maxAge = defaultOptions().MaxAge
+	}
+	expires := time.Now().Add(time.Duration(maxAge) * time.Second)
+	if err := s.backend.save(session.ID, buf.Bytes(), expires); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// remove deletes a session by ID (used by Session.Regenerate)
+func (s *serverStore) remove(id string) error {
+	if id == "" {
+		return nil
+	}
+	return s.backend.delete(id)
+}
+
+// close stops the cleanup goroutine
+func (s *serverStore) close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+func (s *serverStore) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.backend.cleanup(time.Now())
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// generateSessionID returns a random URL and filename safe ID
+func generateSessionID() string {
+	id := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(securecookie.GenerateRandomKey(32))
+	return strings.ToLower(id)
+}
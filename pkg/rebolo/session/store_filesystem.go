@@ -0,0 +1,110 @@
+package session
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// filesystemBackend stores each session in its own file
+type filesystemBackend struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// NewFilesystemSessionStore creates a store that writes session data to
+// files in dir (default: tmp/sessions)
+func NewFilesystemSessionStore(name, dir string, keyPairs ...[]byte) *SessionStore {
+	if dir == "" {
+		dir = filepath.Join("tmp", "sessions")
+	}
+	b := &filesystemBackend{dir: dir}
+	return &SessionStore{
+		store: newServerStore(b, keyPairs...),
+		name:  name,
+	}
+}
+
+func (b *filesystemBackend) path(id string) string {
+	return filepath.Join(b.dir, "session_"+id)
+}
+
+// Files hold the expiry as an 8 byte Unix timestamp followed by the data
+func (b *filesystemBackend) load(id string) ([]byte, bool, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	content, err := os.ReadFile(b.path(id))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(content) < 8 {
+		return nil, false, nil
+	}
+
+	expires := time.Unix(int64(binary.BigEndian.Uint64(content[:8])), 0)
+	if time.Now().After(expires) {
+		return nil, false, nil
+	}
+	return content[8:], true, nil
+}
+
+func (b *filesystemBackend) save(id string, data []byte, expires time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := os.MkdirAll(b.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	content := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(content, uint64(expires.Unix()))
+	content = append(content, data...)
+
+	// Write to a temporary file first so readers never see partial data
+	tmp := b.path(id) + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.path(id))
+}
+
+func (b *filesystemBackend) delete(id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := os.Remove(b.path(id))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (b *filesystemBackend) cleanup(now time.Time) error {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "session_") || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		id := strings.TrimPrefix(name, "session_")
+		if _, found, err := b.load(id); err == nil && !found {
+			b.delete(id)
+		}
+	}
+	return nil
+}
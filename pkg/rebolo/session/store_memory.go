@@ -0,0 +1,65 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// memoryBackend keeps sessions in process memory
+type memoryBackend struct {
+	sessions map[string]memoryEntry
+	mu       sync.RWMutex
+}
+
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewMemorySessionStore creates a store that keeps session data in memory.
+// Sessions are lost on restart and not shared between processes, so use it
+// for development or single-instance deployments.
+func NewMemorySessionStore(name string, keyPairs ...[]byte) *SessionStore {
+	b := &memoryBackend{sessions: make(map[string]memoryEntry)}
+	return &SessionStore{
+		store: newServerStore(b, keyPairs...),
+		name:  name,
+	}
+}
+
+func (b *memoryBackend) load(id string) ([]byte, bool, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	entry, ok := b.sessions[id]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false, nil
+	}
+	return entry.data, true, nil
+}
+
+func (b *memoryBackend) save(id string, data []byte, expires time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sessions[id] = memoryEntry{data: data, expires: expires}
+	return nil
+}
+
+func (b *memoryBackend) delete(id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.sessions, id)
+	return nil
+}
+
+func (b *memoryBackend) cleanup(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, entry := range b.sessions {
+		if now.After(entry.expires) {
+			delete(b.sessions, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package session
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+)
+
+// sqlBackend stores sessions in a database table:
+//
+//	CREATE TABLE sessions (
+//		id VARCHAR(64) PRIMARY KEY,
+//		data TEXT NOT NULL,
+//		expires_at BIGINT NOT NULL
+//	)
+type sqlBackend struct {
+	db    *sql.DB
+	table string
+}
+
+var validTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewSQLSessionStore creates a store that keeps session data in the
+// "sessions" table, creating it if needed
+// Usage: store, err := session.NewSQLSessionStore(app.DB(), "rebolo_session", secret)
+func NewSQLSessionStore(db *sql.DB, name string, keyPairs ...[]byte) (*SessionStore, error) {
+	return NewSQLSessionStoreWithTable(db, "sessions", name, keyPairs...)
+}
+
+// NewSQLSessionStoreWithTable creates a SQL session store using table
+func NewSQLSessionStoreWithTable(db *sql.DB, table, name string, keyPairs ...[]byte) (*SessionStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("sql session store requires a database connection")
+	}
+	if !validTableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid session table name: %s", table)
+	}
+
+	b := &sqlBackend{db: db, table: table}
+	if err := b.createTable(); err != nil {
+		return nil, err
+	}
+
+	return &SessionStore{
+		store: newServerStore(b, keyPairs...),
+		name:  name,
+	}, nil
+}
+
+func (b *sqlBackend) createTable() error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(64) PRIMARY KEY,
+	data TEXT NOT NULL,
+	expires_at BIGINT NOT NULL
+)`, b.table)
+	if _, err := b.db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create session table: %w", err)
+	}
+	return nil
+}
+
+func (b *sqlBackend) load(id string) ([]byte, bool, error) {
+	query := adapters.Rebind(b.db, fmt.Sprintf("SELECT data FROM %s WHERE id = ? AND expires_at > ?", b.table))
+
+	var encoded string
+	err := b.db.QueryRow(query, id, time.Now().Unix()).Scan(&encoded)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// save replaces the row inside a transaction, which works the same on
+// PostgreSQL, MySQL and SQLite
+func (b *sqlBackend) save(id string, data []byte, expires time.Time) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(adapters.Rebind(b.db, fmt.Sprintf("DELETE FROM %s WHERE id = ?", b.table)), id); err != nil {
+		return err
+	}
+
+	insert := adapters.Rebind(b.db, fmt.Sprintf("INSERT INTO %s (id, data, expires_at) VALUES (?, ?, ?)", b.table))
+	if _, err := tx.Exec(insert, id, base64.StdEncoding.EncodeToString(data), expires.Unix()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (b *sqlBackend) delete(id string) error {
+	_, err := b.db.Exec(adapters.Rebind(b.db, fmt.Sprintf("DELETE FROM %s WHERE id = ?", b.table)), id)
+	return err
+}
+
+func (b *sqlBackend) cleanup(now time.Time) error {
+	_, err := b.db.Exec(adapters.Rebind(b.db, fmt.Sprintf("DELETE FROM %s WHERE expires_at <= ?", b.table)), now.Unix())
+	return err
+}
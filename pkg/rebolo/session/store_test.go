@@ -0,0 +1,200 @@
+package session
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// storeFactory opens a store with the given keys. Stores opened by the
+// same factory share their data, as after restarting with new secrets.
+type storeFactory func(t *testing.T, keyPairs ...[]byte) *SessionStore
+
+func testStores(t *testing.T) map[string]storeFactory {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	dir := t.TempDir()
+	memory := &memoryBackend{sessions: make(map[string]memoryEntry)}
+
+	opened := func(t *testing.T, store *SessionStore) *SessionStore {
+		t.Cleanup(func() { store.Close() })
+		return store
+	}
+	return map[string]storeFactory{
+		"cookie": func(t *testing.T, keyPairs ...[]byte) *SessionStore {
+			return NewCookieSessionStore("test_session", keyPairs...)
+		},
+		"memory": func(t *testing.T, keyPairs ...[]byte) *SessionStore {
+			return opened(t, &SessionStore{store: newServerStore(memory, keyPairs...), name: "test_session"})
+		},
+		"filesystem": func(t *testing.T, keyPairs ...[]byte) *SessionStore {
+			return opened(t, NewFilesystemSessionStore("test_session", dir, keyPairs...))
+		},
+		"sql": func(t *testing.T, keyPairs ...[]byte) *SessionStore {
+			store, err := NewSQLSessionStore(db, "test_session", keyPairs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return opened(t, store)
+		},
+	}
+}
+
+// request runs fn with the session of a request carrying cookies and
+// returns the cookies of the response
+func request(t *testing.T, store *SessionStore, cookies []*http.Cookie, fn func(*Session)) []*http.Cookie {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+
+	sess, err := store.Get(req, rec)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	fn(sess)
+	return rec.Result().Cookies()
+}
+
+// save stores values in a new session and returns its cookie
+func save(t *testing.T, store *SessionStore, values map[string]interface{}) []*http.Cookie {
+	t.Helper()
+	return request(t, store, nil, func(sess *Session) {
+		for key, value := range values {
+			sess.Set(key, value)
+		}
+		if err := sess.Save(); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	})
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	for name, open := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			store := open(t, KeyPairs(testSecret)...)
+			cookies := save(t, store, map[string]interface{}{"user_id": "42", "visits": 3, "admin": true})
+
+			request(t, store, cookies, func(sess *Session) {
+				if sess.IsNew() {
+					t.Error("session is new, want the saved one")
+				}
+				if got := sess.GetString("user_id"); got != "42" {
+					t.Errorf("user_id = %q, want 42", got)
+				}
+				if got := sess.GetInt("visits"); got != 3 {
+					t.Errorf("visits = %d, want 3", got)
+				}
+				if !sess.GetBool("admin") {
+					t.Error("admin = false, want true")
+				}
+				if got := sess.GetString("missing"); got != "" {
+					t.Errorf("missing = %q, want empty", got)
+				}
+			})
+
+			// Tampered or unknown cookies start a new session
+			tampered := *cookies[0]
+			tampered.Value = "A" + tampered.Value
+			for _, cookie := range []*http.Cookie{&tampered, {Name: "test_session", Value: "garbage"}} {
+				request(t, store, []*http.Cookie{cookie}, func(sess *Session) {
+					if !sess.IsNew() || sess.GetString("user_id") != "" {
+						t.Errorf("cookie %.20s...: got an existing session, want a new one", cookie.Value)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestStoreDestroy(t *testing.T) {
+	for name, open := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			store := open(t, KeyPairs(testSecret)...)
+			cookies := save(t, store, map[string]interface{}{"user_id": "42"})
+
+			cleared := request(t, store, cookies, func(sess *Session) {
+				if err := sess.Destroy(); err != nil {
+					t.Fatal(err)
+				}
+			})
+			if len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+				t.Errorf("Destroy() set cookies %v, want the session cookie deleted", cleared)
+			}
+
+			// Server-side data is gone even if the client keeps the old cookie
+			if name == "cookie" {
+				return
+			}
+			request(t, store, cookies, func(sess *Session) {
+				if !sess.IsNew() || sess.GetString("user_id") != "" {
+					t.Error("destroyed session still loads")
+				}
+			})
+		})
+	}
+}
+
+func TestSessionRegenerate(t *testing.T) {
+	for name, open := range testStores(t) {
+		if name == "cookie" {
+			continue // Cookie sessions have no server-side ID to fixate
+		}
+		t.Run(name, func(t *testing.T) {
+			store := open(t, KeyPairs(testSecret)...)
+			oldCookies := save(t, store, map[string]interface{}{"cart": "3 items"})
+
+			var oldID, newID string
+			newCookies := request(t, store, oldCookies, func(sess *Session) {
+				oldID = sess.ID()
+				sess.Set("user_id", "42")
+				if err := sess.Regenerate(); err != nil {
+					t.Fatal(err)
+				}
+				newID = sess.ID()
+			})
+			if newID == "" || newID == oldID {
+				t.Fatalf("Regenerate() kept ID %q, want a new one", oldID)
+			}
+
+			request(t, store, newCookies, func(sess *Session) {
+				if sess.GetString("cart") != "3 items" || sess.GetString("user_id") != "42" {
+					t.Error("regenerated session lost its values")
+				}
+			})
+			request(t, store, oldCookies, func(sess *Session) {
+				if !sess.IsNew() {
+					t.Error("old session ID still loads after Regenerate()")
+				}
+			})
+		})
+	}
+}
+
+func TestNewSQLSessionStoreInvalidTable(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := NewSQLSessionStoreWithTable(db, "sessions; DROP TABLE users", "s"); err == nil {
+		t.Error("invalid table name accepted")
+	}
+	if _, err := NewSQLSessionStore(nil, "s"); err == nil {
+		t.Error("nil database accepted")
+	}
+}
@@ -1,12 +1,17 @@
 package tasks
 
 import (
+	"context"
 	"crypto/rand"
+	"database/sql"
 	"encoding/base64"
 	"fmt"
 	"sort"
 	"strings"
 	"sync"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/adapters"
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/auth"
 )
 
 // Task represents a runnable task
@@ -132,7 +137,7 @@ func RunFromArgs(args []string) error {
 
 // DefaultTasks registers default tasks
 func DefaultTasks() {
-	Register("secret", "Generate a cryptographically secure secret key", func(args []string) error {
+	Register("secret", "Generate a cryptographically secure secret key (e.g. for SESSION_SECRET)", func(args []string) error {
 		// Generate 64 random bytes
 		b := make([]byte, 64)
 		_, err := rand.Read(b)
@@ -144,4 +149,65 @@ func DefaultTasks() {
 		fmt.Println(base64.URLEncoding.EncodeToString(b))
 		return nil
 	})
+
+	Register("api-key:create", "Create an API key: api-key:create <name> [user=ID] [scopes...]", createAPIKey)
+}
+
+// createAPIKey stores a new API key in the database from config.yml and
+// prints it once
+func createAPIKey(args []string) error {
+	var name, subject string
+	var scopes []string
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "user="):
+			subject = strings.TrimPrefix(arg, "user=")
+		case name == "":
+			name = arg
+		default:
+			scopes = append(scopes, arg)
+		}
+	}
+	if name == "" {
+		return fmt.Errorf("usage: rebolo task api-key:create <name> [user=ID] [scopes...]")
+	}
+
+	config, err := adapters.NewYAMLConfig().Load()
+	if err != nil {
+		return err
+	}
+	if config.Database.URL == "" {
+		return fmt.Errorf("no database configured in config.yml")
+	}
+
+	database, err := adapters.NewDatabaseFactory().CreateDatabase(config.Database.Driver)
+	if err != nil {
+		return err
+	}
+	if err := database.ConnectWithDSN(config.Database.URL, false); err != nil {
+		return err
+	}
+	defer database.Close()
+
+	db, ok := database.DB().(*sql.DB)
+	if !ok {
+		return fmt.Errorf("database driver %s has no SQL connection", config.Database.Driver)
+	}
+
+	store, err := auth.NewAPIKeyStore(db)
+	if err != nil {
+		return err
+	}
+	key, apiKey, err := store.Create(context.Background(), name, subject, scopes...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🔑 Created API key %s (%s)\n", apiKey.ID, apiKey.Name)
+	if len(scopes) > 0 {
+		fmt.Printf("   Scopes: %s\n", strings.Join(scopes, " "))
+	}
+	fmt.Println("   Store it now, it won't be shown again:")
+	fmt.Println(key)
+	return nil
 }
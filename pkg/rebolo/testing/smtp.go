@@ -0,0 +1,487 @@
+package testing
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMTPMessage is a message received by SMTPServer
+type SMTPMessage struct {
+	From string
+	To   []string
+	Data []byte
+	Auth string // Username used to authenticate, if any
+	TLS  bool   // Whether the message was sent over TLS
+}
+
+// SMTPServer is an in-process fake SMTP server for testing mail delivery.
+// It speaks plain SMTP, with STARTTLS after EnableStartTLS, or implicit TLS
+// when created with NewTLSSMTPServer, using a self-signed certificate that
+// TLSConfig trusts. AUTH PLAIN, LOGIN and CRAM-MD5 accept any credentials
+// unless SetCredentials is called.
+// Usage:
+//
+//	server := testing.NewSMTPServer()
+//	defer server.Close()
+//	sender := mail.NewSMTPSenderWithConfig(mail.SMTPConfig{
+//		Host: server.Host(), Port: server.Port(), TLS: mail.TLSNone,
+//	})
+type SMTPServer struct {
+	listener    net.Listener
+	tlsConfig   *tls.Config // Server side, with the self-signed certificate
+	certPool    *x509.CertPool
+	implicitTLS bool
+	startTLS    bool
+	messages    []SMTPMessage
+	rejected    map[string]bool
+	username    string
+	password    string
+	connections int
+	mu          sync.Mutex
+	wg          sync.WaitGroup
+}
+
+// NewSMTPServer starts a fake SMTP server on a random local port
+func NewSMTPServer() *SMTPServer {
+	return newSMTPServer(false)
+}
+
+// NewTLSSMTPServer starts a fake SMTP server speaking TLS from the first
+// byte, like port 465 (mail.TLSImplicit)
+// Usage:
+//
+//	server := testing.NewTLSSMTPServer()
+//	sender := mail.NewSMTPSenderWithConfig(mail.SMTPConfig{
+//		Host: server.Host(), Port: server.Port(), TLS: mail.TLSImplicit,
+//		TLSConfig: server.TLSConfig(),
+//	})
+func NewTLSSMTPServer() *SMTPServer {
+	return newSMTPServer(true)
+}
+
+func newSMTPServer(implicitTLS bool) *SMTPServer {
+	cert, pool, err := selfSignedCertificate()
+	if err != nil {
+		panic(fmt.Sprintf("failed to create fake SMTP certificate: %v", err))
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("failed to start fake SMTP server: %v", err))
+	}
+	if implicitTLS {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	s := &SMTPServer{
+		listener:    listener,
+		tlsConfig:   tlsConfig,
+		certPool:    pool,
+		implicitTLS: implicitTLS,
+		rejected:    make(map[string]bool),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// selfSignedCertificate creates a certificate for 127.0.0.1 and localhost,
+// and a pool trusting it
+func selfSignedCertificate() (tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "Rebolo fake SMTP"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool, nil
+}
+
+// Addr returns the server address (host:port)
+func (s *SMTPServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Host returns the server host
+func (s *SMTPServer) Host() string {
+	host, _, _ := net.SplitHostPort(s.Addr())
+	return host
+}
+
+// Port returns the server port
+func (s *SMTPServer) Port() int {
+	_, port, _ := net.SplitHostPort(s.Addr())
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// Close stops the server
+func (s *SMTPServer) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// EnableStartTLS advertises STARTTLS (mail.TLSStartTLS, or the default
+// mail.TLSOpportunistic). Clients must trust the certificate, see TLSConfig.
+func (s *SMTPServer) EnableStartTLS() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.startTLS = true
+}
+
+// TLSConfig returns a client TLS config trusting the server certificate,
+// for mail.SMTPConfig.TLSConfig
+func (s *SMTPServer) TLSConfig() *tls.Config {
+	return &tls.Config{RootCAs: s.certPool, ServerName: s.Host()}
+}
+
+// SetCredentials requires AUTH with the given username and password
+func (s *SMTPServer) SetCredentials(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.username = username
+	s.password = password
+}
+
+// Reject makes the server refuse RCPT TO for the given addresses
+func (s *SMTPServer) Reject(addrs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, addr := range addrs {
+		s.rejected[strings.ToLower(addr)] = true
+	}
+}
+
+// Messages returns the received messages in order
+func (s *SMTPServer) Messages() []SMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]SMTPMessage, len(s.messages))
+	copy(result, s.messages)
+	return result
+}
+
+// Connections returns the number of accepted connections
+func (s *SMTPServer) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+// Reset clears received messages and the connection count
+func (s *SMTPServer) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+	s.connections = 0
+}
+
+func (s *SMTPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.connections++
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// smtpSession holds the state of one client connection
+type smtpSession struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+	tls    bool
+	authed string
+	from   string
+	to     []string
+}
+
+func (sess *smtpSession) reply(code int, msg string) {
+	fmt.Fprintf(sess.writer, "%d %s\r\n", code, msg)
+	sess.writer.Flush()
+}
+
+func (sess *smtpSession) readLine() (string, error) {
+	line, err := sess.reader.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func (s *SMTPServer) handle(conn net.Conn) {
+	sess := &smtpSession{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		writer: bufio.NewWriter(conn),
+		tls:    s.implicitTLS,
+	}
+	defer func() { sess.conn.Close() }()
+	sess.reply(220, "localhost Rebolo fake SMTP ready")
+
+	for {
+		line, err := sess.readLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			s.mu.Lock()
+			startTLS := s.startTLS && !sess.tls
+			s.mu.Unlock()
+			fmt.Fprintf(sess.writer, "250-localhost\r\n250-8BITMIME\r\n")
+			if startTLS {
+				fmt.Fprintf(sess.writer, "250-STARTTLS\r\n")
+			}
+			fmt.Fprintf(sess.writer, "250 AUTH PLAIN LOGIN CRAM-MD5\r\n")
+			sess.writer.Flush()
+		case "STARTTLS":
+			s.mu.Lock()
+			startTLS := s.startTLS && !sess.tls
+			s.mu.Unlock()
+			if !startTLS {
+				sess.reply(502, "Command not implemented")
+				continue
+			}
+			sess.reply(220, "Ready to start TLS")
+			if !s.upgrade(sess) {
+				return
+			}
+		case "HELO":
+			sess.reply(250, "localhost")
+		case "AUTH":
+			s.auth(sess, arg)
+		case "MAIL":
+			if !s.authorized(sess) {
+				sess.reply(530, "Authentication required")
+				continue
+			}
+			sess.from = extractAddress(arg)
+			sess.to = nil
+			sess.reply(250, "OK")
+		case "RCPT":
+			addr := extractAddress(arg)
+			s.mu.Lock()
+			rejected := s.rejected[strings.ToLower(addr)]
+			s.mu.Unlock()
+			if rejected {
+				sess.reply(550, "No such user: "+addr)
+				continue
+			}
+			sess.to = append(sess.to, addr)
+			sess.reply(250, "OK")
+		case "DATA":
+			if len(sess.to) == 0 {
+				sess.reply(554, "No valid recipients")
+				continue
+			}
+			sess.reply(354, "End data with <CR><LF>.<CR><LF>")
+			data, err := readData(sess.reader)
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, SMTPMessage{From: sess.from, To: sess.to, Data: data, Auth: sess.authed, TLS: sess.tls})
+			s.mu.Unlock()
+			sess.from, sess.to = "", nil
+			sess.reply(250, "OK: queued")
+		case "RSET":
+			sess.from, sess.to = "", nil
+			sess.reply(250, "OK")
+		case "NOOP":
+			sess.reply(250, "OK")
+		case "QUIT":
+			sess.reply(221, "Bye")
+			return
+		default:
+			sess.reply(502, "Command not implemented")
+		}
+	}
+}
+
+// upgrade switches the session to TLS after STARTTLS. The client starts
+// over with EHLO, so the session state is reset.
+func (s *SMTPServer) upgrade(sess *smtpSession) bool {
+	conn := tls.Server(sess.conn, s.tlsConfig)
+	if err := conn.Handshake(); err != nil {
+		return false
+	}
+	sess.conn = conn
+	sess.reader = bufio.NewReader(conn)
+	sess.writer = bufio.NewWriter(conn)
+	sess.tls = true
+	sess.authed, sess.from, sess.to = "", "", nil
+	return true
+}
+
+func (s *SMTPServer) authorized(sess *smtpSession) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.username == "" || sess.authed != ""
+}
+
+// auth handles AUTH PLAIN, AUTH LOGIN and AUTH CRAM-MD5
+func (s *SMTPServer) auth(sess *smtpSession, arg string) {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+
+	var username, password string
+	switch strings.ToUpper(mechanism) {
+	case "PLAIN":
+		if initial == "" {
+			sess.reply(334, "")
+			line, err := sess.readLine()
+			if err != nil {
+				return
+			}
+			initial = line
+		}
+		decoded, err := base64.StdEncoding.DecodeString(initial)
+		if err != nil {
+			sess.reply(501, "Invalid credentials encoding")
+			return
+		}
+		parts := strings.Split(string(decoded), "\x00")
+		if len(parts) != 3 {
+			sess.reply(501, "Invalid credentials")
+			return
+		}
+		username, password = parts[1], parts[2]
+	case "LOGIN":
+		values := make([]string, 0, 2)
+		for _, prompt := range []string{"Username:", "Password:"} {
+			sess.reply(334, base64.StdEncoding.EncodeToString([]byte(prompt)))
+			line, err := sess.readLine()
+			if err != nil {
+				return
+			}
+			decoded, err := base64.StdEncoding.DecodeString(line)
+			if err != nil {
+				sess.reply(501, "Invalid credentials encoding")
+				return
+			}
+			values = append(values, string(decoded))
+		}
+		username, password = values[0], values[1]
+	case "CRAM-MD5":
+		challenge := fmt.Sprintf("<%d.%d@localhost>", time.Now().UnixNano(), s.Port())
+		sess.reply(334, base64.StdEncoding.EncodeToString([]byte(challenge)))
+		line, err := sess.readLine()
+		if err != nil {
+			return
+		}
+		decoded, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			sess.reply(501, "Invalid credentials encoding")
+			return
+		}
+		user, digest, ok := strings.Cut(string(decoded), " ")
+		if !ok {
+			sess.reply(501, "Invalid credentials")
+			return
+		}
+
+		// The password never travels, check the digest it produces instead
+		s.mu.Lock()
+		expected := s.password
+		s.mu.Unlock()
+		mac := hmac.New(md5.New, []byte(expected))
+		mac.Write([]byte(challenge))
+		username = user
+		if hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(digest)) {
+			password = expected
+		}
+	default:
+		sess.reply(504, "Unrecognized authentication type")
+		return
+	}
+
+	s.mu.Lock()
+	valid := s.username == "" || (username == s.username && password == s.password)
+	s.mu.Unlock()
+
+	if !valid {
+		sess.reply(535, "Authentication credentials invalid")
+		return
+	}
+	sess.authed = username
+	sess.reply(235, "Authentication successful")
+}
+
+// extractAddress returns the address in "FROM:<user@example.com> BODY=8BITMIME"
+func extractAddress(arg string) string {
+	start := strings.Index(arg, "<")
+	end := strings.Index(arg, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return arg[start+1 : end]
+}
+
+// readData reads a DATA payload up to the terminating dot, undoing dot-stuffing
+func readData(reader *bufio.Reader) ([]byte, error) {
+	var data []byte
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if line == ".\r\n" || line == ".\n" {
+			return data, nil
+		}
+		if strings.HasPrefix(line, ".") {
+			line = line[1:]
+		}
+		data = append(data, line...)
+	}
+}
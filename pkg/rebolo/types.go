@@ -23,9 +23,11 @@ type (
 	FlashMessage     = session.FlashMessage
 	ErrorHandler     = errors.ErrorHandler
 	ErrorHandlers    = errors.ErrorHandlers
+	HTTPError        = errors.HTTPError
 	MiddlewareFunc   = middleware.MiddlewareFunc
 	MiddlewareConfig = middleware.MiddlewareConfig
 	MiddlewareStack  = middleware.MiddlewareStack
+	CORSConfig       = middleware.CORSConfig
 	FileWatcher      = watcher.FileWatcher
 	TestApp          = testing.TestApp
 	ValidationError  = validation.ValidationError
@@ -41,6 +43,7 @@ var (
 	GetSession            = session.GetSession
 	GetFlash              = session.GetFlash
 	NewErrorHandlers      = errors.NewErrorHandlers
+	NewHTTPError          = errors.NewHTTPError
 	NewMiddlewareStack    = middleware.NewMiddlewareStack
 	CORSMiddleware        = middleware.CORSMiddleware
 	ValidateStruct        = validation.ValidateStruct
@@ -49,9 +52,10 @@ var (
 	BindAndValidate       = validation.BindAndValidate
 )
 
-// NewTestApp creates a new test app wrapping an application
+// NewTestApp creates a new test app wrapping an application. Requests go
+// through the middleware registered so far.
 func NewTestApp(app *Application) *TestApp {
-	return testing.NewTestApp(app.router)
+	return testing.NewTestApp(app.Handler())
 }
 
 // ContextMiddleware wraps a ContextHandler to work with standard http.Handler
@@ -60,6 +64,12 @@ func (a *Application) ContextMiddleware(handler ContextHandler) http.HandlerFunc
 		ctx := NewContext(w, r, a)
 
 		if err := handler(ctx); err != nil {
+			// Errors carrying a status (e.g. 403 from Authorize) use its handler
+			if code := errors.StatusCode(err); code != http.StatusInternalServerError {
+				a.HandleError(w, r, err, code)
+				return
+			}
+
 			// Use custom error handler
 			a.InternalErrorHandler(w, r, err)
 		}
@@ -9,6 +9,10 @@ import (
 	"strings"
 )
 
+// MaxMultipartMemory is the part of multipart forms kept in memory by Bind
+// (server.multipart_memory in config.yml)
+var MaxMultipartMemory int64 = 8 << 20
+
 // Bind binds request data to a struct
 // Supports form data, JSON, and query parameters
 func Bind(r *http.Request, v interface{}) error {
@@ -37,8 +41,8 @@ func Bind(r *http.Request, v interface{}) error {
 
 // bindMultipart binds multipart form data (including files) to struct
 func bindMultipart(r *http.Request, v interface{}) error {
-	// Parse multipart form (32MB max memory)
-	if err := r.ParseMultipartForm(32 << 20); err != nil {
+	// Parse multipart form (larger files are stored in temporary files)
+	if err := r.ParseMultipartForm(MaxMultipartMemory); err != nil {
 		return err
 	}
 
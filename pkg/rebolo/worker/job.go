@@ -1,6 +1,16 @@
 package worker
 
-import "encoding/json"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Palaciodiego008/rebololang/pkg/rebolo/logging"
+)
+
+// RequestIDArg is the Args key holding the ID of the request that enqueued
+// the job (see WithRequestID)
+const RequestIDArg = "request_id"
 
 // Args are the arguments passed into a job
 type Args map[string]interface{}
@@ -10,6 +20,12 @@ func (a Args) String() string {
 	return string(b)
 }
 
+// RequestID returns the ID of the request that enqueued the job, or ""
+func (a Args) RequestID() string {
+	id, _ := a[RequestIDArg].(string)
+	return id
+}
+
 // Job to be processed by a Worker
 type Job struct {
 	// Queue the job should be placed into
@@ -24,3 +40,30 @@ func (j Job) String() string {
 	b, _ := json.Marshal(j)
 	return string(b)
 }
+
+// summary identifies the job in logs without its Args, which may hold
+// private data (mail bodies, reset links)
+func (j Job) summary() string {
+	s := fmt.Sprintf("handler=%s queue=%s", j.Handler, j.Queue)
+	if id := j.Args.RequestID(); id != "" {
+		s += " request_id=" + id
+	}
+	return s
+}
+
+// WithRequestID returns a copy of job carrying the request ID of ctx in its
+// Args, so the job logs can be traced back to the request
+// Usage: app.Perform(worker.WithRequestID(r.Context(), job))
+func WithRequestID(ctx context.Context, job Job) Job {
+	id := logging.RequestID(ctx)
+	if id == "" {
+		return job
+	}
+	args := make(Args, len(job.Args)+1)
+	for k, v := range job.Args {
+		args[k] = v
+	}
+	args[RequestIDArg] = id
+	job.Args = args
+	return job
+}
@@ -100,10 +100,10 @@ func (w *Simple) Perform(job Job) error {
 		return fmt.Errorf("worker is not ready to perform a job: %v", err)
 	}
 
-	w.logger.Printf("performing job %s", job)
+	w.logger.Printf("performing job %s", job.summary())
 
 	if job.Handler == "" {
-		err := fmt.Errorf("no handler name given: %s", job)
+		err := fmt.Errorf("no handler name given: %s", job.summary())
 		w.logger.Println("ERROR:", err)
 		return err
 	}
@@ -119,7 +119,7 @@ func (w *Simple) Perform(job Job) error {
 			if err != nil {
 				w.logger.Println("ERROR:", err)
 			}
-			w.logger.Printf("completed job %s", job)
+			w.logger.Printf("completed job %s", job.summary())
 		}()
 		return nil
 	}